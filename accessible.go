@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// accessible runs the interface for screen readers: in the normal screen
+// rather than the alternate one, with the selection marked by text instead
+// of reverse video, and announcing each newly selected item on a line of
+// its own.
+var accessible = false
+
+// useAccessibleStyles marks the selection with a leading ">" and draws the
+// now-playing bar without reverse video.
+func useAccessibleStyles() {
+	selectedStyle = lipgloss.NewStyle().SetString(">")
+	nowPlayingStyle = lipgloss.NewStyle()
+}
+
+// selection describes what is selected in the open view, such as "3 of 120:
+// Alien (1979)  ★★★★☆", for announcing. It is "" when nothing is.
+func (m model) selection() string {
+	switch {
+	case m.nowPlaying && m.playing != nil:
+		if m.chapterMode && m.chapterCursor < len(m.playing.chapters) {
+			return trf("Chapter %d of %d: %s", m.chapterCursor+1, len(m.playing.chapters), m.playing.chapters[m.chapterCursor].Title)
+		}
+		return ""
+	case m.paletteMode:
+		matches := m.paletteMatches()
+		if m.paletteCursor >= len(matches) {
+			return ""
+		}
+		return trf("%d of %d: %s", m.paletteCursor+1, len(matches), matches[m.paletteCursor].name)
+	case m.editionMode:
+		editions := m.editions[m.editionVideo]
+		if m.editionCursor >= len(editions) {
+			return ""
+		}
+		return trf("%d of %d: %s", m.editionCursor+1, len(editions), filepath.Base(editions[m.editionCursor]))
+	case m.rootMode:
+		name := tr("All roots")
+		if m.rootCursor > 0 && m.rootCursor <= len(videoDirs) {
+			name = videoDirs[m.rootCursor-1]
+		}
+		return trf("%d of %d: %s", m.rootCursor+1, len(videoDirs)+1, name)
+	case m.treeMode:
+		path := crumbs(m.treeDir)
+		if m.crumbMode && m.crumbCursor < len(path) {
+			return trf("%d of %d: %s", m.crumbCursor+1, len(path), crumbName(path[m.crumbCursor]))
+		}
+		entries := m.treeEntries(m.treeDir)
+		if m.treeCursor >= len(entries) {
+			return ""
+		}
+		return trf("%d of %d: %s", m.treeCursor+1, len(entries), filepath.Base(entries[m.treeCursor].path))
+	case m.actionMode, m.healthMode, m.verifyMode, m.sheetMode:
+		return ""
+	case m.dashboard && !m.pinMode:
+		videos := m.dashboardVideos()
+		if m.dashCursor >= len(videos) {
+			return ""
+		}
+		return trf("%d of %d: %s", m.dashCursor+1, len(videos), m.label(videos[m.dashCursor]))
+	case len(m.videos) > 0:
+		return trf("%d of %d: %s", m.cursor+1, len(m.videos), ansi.Strip(m.renderDetail(m.videos[m.cursor])))
+	}
+	return ""
+}
+
+// announce prints the selection of next above the interface when it
+// differs from before, the selection when the key was pressed, so a screen
+// reader reads it out.
+func announce(before string, next tea.Model, cmd tea.Cmd) tea.Cmd {
+	m, ok := next.(model)
+	if !ok {
+		return cmd
+	}
+	if now := m.selection(); now != "" && now != before {
+		return tea.Batch(cmd, tea.Println(now))
+	}
+	return cmd
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listAction is an action offered in the action menu: a plugin action or a
+// built-in one such as a transcode preset. source names where it comes from.
+type listAction struct {
+	source string
+	action pluginAction
+	run    func(video string) (string, error)
+}
+
+type pluginActionMsg struct {
+	message string
+	err     error
+}
+
+func availableActions() []listAction {
+	var actions []listAction
+	for _, p := range plugins {
+		for _, a := range p.Actions {
+			run := func(video string) (string, error) {
+				return runPluginAction(p, a.Name, video)
+			}
+			actions = append(actions, listAction{p.Name, a, run})
+		}
+	}
+	return append(actions, transcodeActions()...)
+}
+
+func (m model) updateActions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	actions := availableActions()
+	switch keys().command("actions", msg.String()) {
+	case "up":
+		if m.actionCursor > 0 {
+			m.actionCursor--
+		}
+	case "down":
+		if m.actionCursor < len(actions)-1 {
+			m.actionCursor++
+		}
+	case "run":
+		m.actionMode = false
+		if m.actionCursor < len(actions) && len(m.videos) > 0 {
+			a := actions[m.actionCursor]
+			video := m.videos[m.cursor]
+			m.status = "Running " + a.action.Name + "..."
+			return m, func() tea.Msg {
+				message, err := a.run(video)
+				return pluginActionMsg{message, err}
+			}
+		}
+	case "close":
+		m.actionMode = false
+	}
+	return m, nil
+}
+
+func (m model) viewActions() string {
+	s := keys().help("Actions - %s to run on the selected video, %s to cancel", "actions", "run", "close") + "\n\n"
+	for i, a := range availableActions() {
+		line := fmt.Sprintf("%s: %s", a.source, a.action.Name)
+		if a.action.Description != "" {
+			line += " - " + a.action.Description
+		}
+		if i == m.actionCursor {
+			s += selectedStyle.Render(line) + "\n"
+		} else {
+			s += line + "\n"
+		}
+	}
+	return s
+}
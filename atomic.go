@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces path with data while holding path's lock file,
+// path plus ".lock", so two instances saving at once take turns.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return replaceFile(path, data)
+}
+
+// saveMerged replaces path with what merge returns for the contents saved
+// there now, nil when there are none, while holding path's lock file. A
+// file another instance may have saved since it was loaded is merged this
+// way rather than overwritten, and the lock is held from reading it to
+// replacing it so that no save comes in between.
+func saveMerged(path string, merge func(saved []byte) ([]byte, error)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	saved, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	data, err := merge(saved)
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, data)
+}
+
+// mergeChanges returns saved, a map as another instance saved it, with the
+// changes made to ours since it was loaded as base: entries added to or
+// changed in ours replace those of saved, and entries removed from ours are
+// removed from it.
+func mergeChanges[K comparable, V any](saved, base, ours map[K]V) map[K]V {
+	merged := make(map[K]V, len(saved))
+	for k, v := range saved {
+		merged[k] = v
+	}
+	for k, v := range ours {
+		if b, ok := base[k]; !ok || !sameJSON(b, v) {
+			merged[k] = v
+		}
+	}
+	for k := range base {
+		if _, ok := ours[k]; !ok {
+			delete(merged, k)
+		}
+	}
+	return merged
+}
+
+// sameJSON reports whether a and b are saved alike. Unlike ==, it holds for
+// a time read back from a file and the time it was saved from.
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// replaceFile replaces path with data so that readers and crashes only ever
+// see the old or the new contents: it writes to a temporary file in the same
+// directory, flushes it to disk and renames it over path.
+func replaceFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
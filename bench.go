@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// benchQueryTime is how long each query is repeated for, so that fast
+// queries are timed over many runs.
+const benchQueryTime = 200 * time.Millisecond
+
+// bench is the bench subcommand. It times a full walk and a cached walk of
+// each library root, loading the index, state and library cache, and
+// matching queries against the whole library: args, each one a filter such
+// as "year:>=2000 !sample", or a few typical ones. Nothing is saved, so it
+// can be run against a live library.
+func bench(args []string) error {
+	if len(videoDirs) == 0 {
+		return fmt.Errorf("no library roots configured, set VIDEO_DIR")
+	}
+	fmt.Println("Scans (full walk, then again reusing unchanged folders):")
+	var videos []string
+	for _, root := range videoDirs {
+		var found []string
+		opts := cfg.walkOptions()
+		opts.progress = newScanProgress()
+		start := time.Now()
+		dirs, err := walkFiles(root, opts, nil, func(path string) {
+			if isLibraryFile(path) {
+				found = append(found, path)
+			}
+		})
+		cold := time.Since(start)
+		if err != nil {
+			fmt.Printf("  %s: %v\n", root, err)
+			continue
+		}
+		start = time.Now()
+		walkFiles(root, cfg.walkOptions(), dirs, func(string) {})
+		warm := time.Since(start)
+		workers, storage := walkWorkers(root)
+		if opts.workers > 0 {
+			workers, storage = opts.workers, "scan_workers"
+		}
+		fmt.Printf("  %s (%s, %d workers): %d folders, %d videos, %s full, %s cached\n",
+			root, storage, workers, opts.progress.dirs.Load(), len(found), formatBench(cold), formatBench(warm))
+		videos = append(videos, found...)
+	}
+
+	fmt.Println("Loading:")
+	var ix *index
+	var st *state
+	loads := []struct {
+		name string
+		load func() error
+	}{
+		{"index", func() (err error) { ix, err = loadIndex(filepath.Join(dataDir(), "index.json")); return err }},
+		{"state", func() (err error) { st, err = loadState(filepath.Join(profileDir(), "state.json")); return err }},
+		{"library cache", func() error { loadLibraryCache(libraryCachePath()); return nil }},
+	}
+	for _, l := range loads {
+		start := time.Now()
+		if err := l.load(); err != nil {
+			return fmt.Errorf("loading the %s: %w", l.name, err)
+		}
+		fmt.Printf("  %s: %s\n", l.name, formatBench(time.Since(start)))
+	}
+	fmt.Printf("  index entries: %d\n", len(ix.Entries))
+
+	queries := args
+	if len(queries) == 0 {
+		queries = []string{"year:1990-1999", "!sample", "stars:>=4"}
+		if len(videos) > 0 {
+			if words := strings.Fields(normalizedName(videos[len(videos)/2])); len(words) > 0 {
+				queries = append([]string{words[0]}, queries...)
+			}
+		}
+	}
+	fmt.Printf("Queries over %d videos (with metadata):\n", len(videos))
+	for _, filter := range queries {
+		q := parseQuery(splitWords(filter))
+		q.metadata = true
+		runs, matched := 0, 0
+		start := time.Now()
+		for runs < 3 || time.Since(start) < benchQueryTime {
+			matched = 0
+			for _, video := range videos {
+				if q.matches(video, ix, st) {
+					matched++
+				}
+			}
+			runs++
+		}
+		fmt.Printf("  %q: %d matches, %s\n", filter, matched, formatBench(time.Since(start)/time.Duration(runs)))
+	}
+	return nil
+}
+
+// formatBench rounds d to a precision that suits its size.
+func formatBench(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
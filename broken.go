@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// brokenMsg reports the videos that look incomplete, with the reason
+// brokenReason gave for each.
+type brokenMsg map[string]string
+
+// checkFiles looks for empty, truncated and zero-filled videos in the
+// background after a scan, and with probe_files set also has ffprobe read
+// each one.
+func checkFiles(ctx context.Context, videos []string) tea.Cmd {
+	videos = append([]string(nil), videos...)
+	return func() tea.Msg {
+		probe := false
+		if cfg.ProbeFiles {
+			_, err := exec.LookPath("ffprobe")
+			probe = err == nil
+		}
+		broken := brokenMsg{}
+		for _, video := range videos {
+			if ctx.Err() != nil {
+				break
+			}
+			if reason := brokenReason(ctx, video, probe); reason != "" {
+				logger.Warn("video looks broken", "path", video, "reason", reason)
+				broken[video] = reason
+			}
+		}
+		return broken
+	}
+}
+
+// brokenReason reports why video looks like an incomplete download: "empty"
+// for a zero-byte file, "truncated" when its container claims more data
+// than the file holds, "incomplete" when it starts with zeros (as files a
+// torrent client has only allocated do) and, with probe set, "unreadable"
+// when ffprobe cannot make sense of it. It returns "" for files that look
+// fine and for paths it cannot check, such as URLs.
+func brokenReason(ctx context.Context, video string, probe bool) string {
+	if !filepath.IsAbs(video) {
+		return ""
+	}
+	f, err := os.Open(video)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	if info.Size() == 0 {
+		return "empty"
+	}
+	head := make([]byte, 4096)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+	head = head[:n]
+	if len(bytes.Trim(head, "\x00")) == 0 {
+		return "incomplete"
+	}
+	if truncated(f, head, info.Size()) {
+		return "truncated"
+	}
+	if probe {
+		if _, err := videoDuration(ctx, video); err != nil && ctx.Err() == nil {
+			return "unreadable"
+		}
+	}
+	return ""
+}
+
+// truncated reports whether the container of a file of the given size,
+// starting with head, says it should be longer. It understands MP4 and
+// QuickTime, Matroska and WebM, and AVI; other files are never truncated.
+func truncated(f io.ReaderAt, head []byte, size int64) bool {
+	switch {
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		return 8+int64(binary.LittleEndian.Uint32(head[4:8])) > size
+	case len(head) >= 4 && bytes.Equal(head[:4], []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return truncatedMatroska(head, size)
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return truncatedMP4(f, size)
+	}
+	return false
+}
+
+// truncatedMP4 walks the top-level boxes of an MP4 file and reports whether
+// one runs past the end of the file, or the file ends without the "moov"
+// box that indexes it.
+func truncatedMP4(f io.ReaderAt, size int64) bool {
+	var offset int64
+	moov := false
+	for offset < size {
+		var header [16]byte
+		if n, _ := f.ReadAt(header[:], offset); n < 8 {
+			return true
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		switch boxSize {
+		case 0:
+			// The box runs to the end of the file.
+			return !moov && string(header[4:8]) != "moov"
+		case 1:
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if boxSize < 8 {
+			return false
+		}
+		if string(header[4:8]) == "moov" {
+			moov = true
+		}
+		offset += boxSize
+	}
+	return offset > size || !moov
+}
+
+// truncatedMatroska reads the size of the Segment following the EBML header
+// at the start of a Matroska file and reports whether it is larger than the
+// file. Segments written live have an unknown size and are never truncated.
+func truncatedMatroska(head []byte, size int64) bool {
+	pos := 4
+	headerSize, n, _ := readVint(head[pos:])
+	if n == 0 {
+		return false
+	}
+	pos += n + int(headerSize)
+	if pos+4 > len(head) || !bytes.Equal(head[pos:pos+4], []byte{0x18, 0x53, 0x80, 0x67}) {
+		return false
+	}
+	pos += 4
+	segmentSize, n, unknown := readVint(head[pos:])
+	if n == 0 || unknown {
+		return false
+	}
+	return int64(pos+n)+int64(segmentSize) > size
+}
+
+// readVint decodes an EBML variable-length integer, returning its value,
+// its length in bytes (0 if b does not hold one) and whether all its value
+// bits are set, which EBML uses for "unknown".
+func readVint(b []byte) (value uint64, length int, unknown bool) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, false
+	}
+	for length = 1; b[0]&(0x80>>(length-1)) == 0; length++ {
+	}
+	if len(b) < length {
+		return 0, 0, false
+	}
+	value = uint64(b[0] & (0xff >> length))
+	for _, c := range b[1:length] {
+		value = value<<8 | uint64(c)
+	}
+	return value, length, value == 1<<(7*length)-1
+}
+
+// brokenBadge is the warning shown next to a video that looks incomplete.
+func (m model) brokenBadge(video string) string {
+	if reason := m.broken[video]; reason != "" {
+		return failStyle.Render("⚠ " + reason)
+	}
+	return ""
+}
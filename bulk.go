@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bulkCommands are the palette commands acting on every listed video, that
+// is everything matching the filter. Each asks first, giving the count.
+// They have no keys unless bound in the [keys] settings.
+func (m model) bulkCommands() []paletteCommand {
+	if len(m.videos) == 0 {
+		return nil
+	}
+	return []paletteCommand{
+		{name: "Mark all listed videos watched", command: "mark-all-watched"},
+		{name: "Tag all listed videos", command: "tag-all"},
+		{name: "Export the listed videos to a playlist", command: "export-playlist"},
+	}
+}
+
+// bulkCommand runs the bulk command of the list named command.
+func (m model) bulkCommand(command string) (tea.Model, tea.Cmd) {
+	if len(m.videos) == 0 {
+		return m, nil
+	}
+	switch command {
+	case "mark-all-watched":
+		videos := m.listedFiles(m.videos)
+		return m.ask("bulk-watched", fmt.Sprintf("Mark %d videos watched?", len(videos)), func(m *model) tea.Cmd {
+			m.markAllWatched(videos)
+			return nil
+		})
+	case "tag-all":
+		m.tagMode = true
+		m.bulkTag = true
+		m.tagInput.Focus()
+		return m, textinput.Blink
+	case "export-playlist":
+		m.playlistMode = true
+		m.playlistInput.SetValue("~/movie-launcher.m3u")
+		m.playlistInput.CursorEnd()
+		m.playlistInput.Focus()
+		return m, textinput.Blink
+	}
+	return m, nil
+}
+
+// listedFiles expands videos, as listed, into the files they stand for:
+// each edition of a film found in several, and each part of a film split
+// across files.
+func (m model) listedFiles(videos []string) []string {
+	files := make([]string, 0, len(videos))
+	for _, video := range videos {
+		editions := m.editions[video]
+		if editions == nil {
+			editions = []string{video}
+		}
+		for _, edition := range editions {
+			if parts := m.parts[edition]; parts != nil {
+				files = append(files, parts...)
+			} else {
+				files = append(files, edition)
+			}
+		}
+	}
+	return files
+}
+
+// markAllWatched marks videos watched, keeping when those already watched
+// were.
+func (m *model) markAllWatched(videos []string) {
+	marked := 0
+	for _, video := range videos {
+		if m.state.Watched[video].IsZero() {
+			markWatched(video, m.state)
+			marked++
+		}
+	}
+	m.status = fmt.Sprintf("Marked %d videos watched", marked)
+	if err := m.state.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving state: %v", err)
+	}
+	m.refresh()
+}
+
+// tagAll asks before applying the tags in input to videos, as tagVideos
+// does.
+func (m model) tagAll(videos []string, input string) (tea.Model, tea.Cmd) {
+	if strings.TrimSpace(input) == "" {
+		return m, nil
+	}
+	return m.ask("bulk-tag", fmt.Sprintf("Apply %q to %d videos?", input, len(videos)), func(m *model) tea.Cmd {
+		m.tagVideos(videos, input)
+		if m.status == "" {
+			m.status = fmt.Sprintf("Tagged %d videos", len(videos))
+		}
+		m.refresh()
+		return nil
+	})
+}
+
+// exportPlaylist asks before writing videos to the M3U playlist at path.
+func (m model) exportPlaylist(videos []string, path string) (tea.Model, tea.Cmd) {
+	path, err := expandHome(strings.TrimSpace(path))
+	if err != nil || path == "" {
+		m.status = "No playlist written"
+		return m, nil
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		m.status = fmt.Sprintf("Error writing playlist: %v", err)
+		return m, nil
+	}
+	return m.ask("bulk-playlist", fmt.Sprintf("Write %d videos to %s?", len(videos), path), func(m *model) tea.Cmd {
+		if err := writePlaylist(path, videos, m.index); err != nil {
+			m.status = fmt.Sprintf("Error writing playlist: %v", err)
+		} else {
+			m.status = fmt.Sprintf("Wrote %d videos to %s", len(videos), path)
+		}
+		return nil
+	})
+}
+
+// writePlaylist writes videos to path as an extended M3U playlist, which
+// mpv, VLC and most other players open, with their titles.
+func writePlaylist(path string, videos []string, ix *index) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, video := range videos {
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n%s\n", videoTitle(video, ix), video)
+	}
+	return replaceFile(path, []byte(b.String()))
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// checksumRecord is the size and SHA-256 of a file when it was last
+// verified.
+type checksumRecord struct {
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	Checked time.Time `json:"checked"`
+}
+
+// checksumProblem is a file the last verification found fault with.
+type checksumProblem struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// verifyReport sums up a verification run for the report view.
+type verifyReport struct {
+	Time     time.Time         `json:"time"`
+	Checked  int               `json:"checked"`
+	OK       int               `json:"ok"`
+	New      int               `json:"new"`
+	Problems []checksumProblem `json:"problems,omitempty"`
+}
+
+// checksumStore is checksums.json in the data directory. Files are keyed
+// relative to their library root, like the sync file, so a library copied
+// to another disk is checked against the checksums of the original.
+type checksumStore struct {
+	path   string
+	Files  map[string]*checksumRecord `json:"files"`
+	Report verifyReport               `json:"report"`
+}
+
+func checksumPath() string {
+	return filepath.Join(dataDir(), "checksums.json")
+}
+
+func loadChecksums(path string) (*checksumStore, error) {
+	cs := &checksumStore{path: path, Files: map[string]*checksumRecord{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cs); err != nil {
+		return nil, err
+	}
+	if cs.Files == nil {
+		cs.Files = map[string]*checksumRecord{}
+	}
+	return cs, nil
+}
+
+func (cs *checksumStore) save() error {
+	data, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cs.path, data)
+}
+
+// fileChecksum hashes the whole of path.
+func fileChecksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verify hashes video and compares it with its stored checksum, returning
+// "ok", "new" for a file seen the first time (whose checksum is stored),
+// "corrupt" when the content changed but not the size, as with bit-rot,
+// "incomplete" when the file shrank, as with an interrupted copy, "changed"
+// when it grew, or "unreadable". A changed file's checksum is replaced, as
+// is a corrupt or incomplete one's with update set (which reports
+// "updated").
+func (cs *checksumStore) verify(video string, update bool) string {
+	size, sum, err := fileChecksum(video)
+	if err != nil {
+		logger.Warn("checksum failed", "path", video, "err", err)
+		return "unreadable"
+	}
+	key := syncKey(video)
+	rec := cs.Files[key]
+	status := "ok"
+	switch {
+	case rec == nil:
+		status = "new"
+	case rec.SHA256 == sum:
+	case update:
+		status = "updated"
+	case size < rec.Size:
+		return "incomplete"
+	case size == rec.Size:
+		return "corrupt"
+	default:
+		status = "changed"
+	}
+	cs.Files[key] = &checksumRecord{Size: size, SHA256: sum, Checked: time.Now()}
+	return status
+}
+
+// verifyAll verifies videos, calling progress after each one, and stores the
+// report and checksums. Once ctx is cancelled it stores what it has verified
+// so far and returns ctx's error.
+func (cs *checksumStore) verifyAll(ctx context.Context, videos []string, update bool, progress func(video, status string)) error {
+	report := verifyReport{Time: time.Now()}
+	for _, video := range videos {
+		if ctx.Err() != nil {
+			break
+		}
+		if !filepath.IsAbs(video) {
+			continue
+		}
+		status := cs.verify(video, update)
+		report.Checked++
+		switch status {
+		case "ok":
+			report.OK++
+		case "new":
+			report.New++
+		default:
+			report.Problems = append(report.Problems, checksumProblem{Path: video, Status: status})
+		}
+		if progress != nil {
+			progress(video, status)
+		}
+	}
+	cs.Report = report
+	if err := cs.save(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// summary describes the report in one line, such as "12 files checked:
+// 10 ok, 1 new, 1 corrupt".
+func (r verifyReport) summary() string {
+	counts := map[string]int{}
+	for _, p := range r.Problems {
+		counts[p.Status]++
+	}
+	parts := []string{fmt.Sprintf("%d ok", r.OK), fmt.Sprintf("%d new", r.New)}
+	for _, status := range []string{"corrupt", "incomplete", "changed", "updated", "unreadable"} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	return fmt.Sprintf("%d files checked: %s", r.Checked, strings.Join(parts, ", "))
+}
+
+// failed counts the problems that mean a file is damaged, rather than
+// merely changed or updated on purpose.
+func (r verifyReport) failed() int {
+	n := 0
+	for _, p := range r.Problems {
+		if p.Status == "corrupt" || p.Status == "incomplete" || p.Status == "unreadable" {
+			n++
+		}
+	}
+	return n
+}
+
+// verifyChecksums is the verify subcommand: it hashes the videos matching
+// keywords, printing every file that is not ok, and fails when any of them
+// looks damaged. With --update the current content of corrupt and
+// incomplete files is accepted instead.
+func verifyChecksums(ctx context.Context, args []string, ix *index, st *state) error {
+	update := slices.Contains(args, "--update")
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "--update" })
+	videos, err := searchVideos(ctx, args, ix, st)
+	if err != nil {
+		return err
+	}
+	cs, err := loadChecksums(checksumPath())
+	if err != nil {
+		return err
+	}
+	err = cs.verifyAll(ctx, videos, update, func(video, status string) {
+		if status != "ok" {
+			fmt.Printf("%-10s %s\n", status, displayPath(video))
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Interrupted")
+	} else if err != nil {
+		return err
+	}
+	fmt.Println(cs.Report.summary())
+	if n := cs.Report.failed(); n > 0 {
+		return fmt.Errorf("%d files failed verification", n)
+	}
+	return nil
+}
+
+// verifyMsg carries the report of a verification started from the report
+// view.
+type verifyMsg struct {
+	report verifyReport
+	err    error
+}
+
+// loadVerifyReport reads the report of the last verification.
+func loadVerifyReport() tea.Msg {
+	cs, err := loadChecksums(checksumPath())
+	if err != nil {
+		return verifyMsg{err: err}
+	}
+	return verifyMsg{report: cs.Report}
+}
+
+// verifyVideos verifies videos in the background until ctx is cancelled.
+func verifyVideos(ctx context.Context, videos []string) tea.Cmd {
+	videos = append([]string(nil), videos...)
+	return func() tea.Msg {
+		cs, err := loadChecksums(checksumPath())
+		if err != nil {
+			return verifyMsg{err: err}
+		}
+		err = cs.verifyAll(ctx, videos, false, nil)
+		return verifyMsg{report: cs.Report, err: err}
+	}
+}
+
+func (m model) updateVerify(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch keys().command("checksums", msg.String()) {
+	case "verify":
+		if !m.verifying {
+			m.verifying = true
+			return m, verifyVideos(m.viewContext(), m.videos)
+		}
+	case "close":
+		// Leaving stops a run, keeping what it verified so far.
+		m.verifyMode = false
+		m.leaveView()
+	}
+	return m, nil
+}
+
+func (m model) viewVerify() string {
+	s := keys().help("Checksums - %s to verify the listed videos, %s to close", "checksums", "verify", "close") + "\n\n"
+	switch {
+	case m.verifying:
+		s += fmt.Sprintf("verifying %d videos...\n\n", len(m.videos))
+	case m.verifyErr != nil:
+		s += failStyle.Render("Error: "+m.verifyErr.Error()) + "\n\n"
+	}
+	r := m.verifyReport
+	if r.Time.IsZero() {
+		return s + fmt.Sprintf("Never verified; run %s here or movie-launcher verify.\n", keys().label("checksums", "verify"))
+	}
+	s += fmt.Sprintf("Last run %s\n%s\n\n", formatDateTime(r.Time), r.summary())
+	if len(r.Problems) == 0 {
+		return s + okStyle.Render("No problems found") + "\n"
+	}
+	room := max(m.height-8, 5)
+	for i, p := range r.Problems {
+		if i == room {
+			s += fmt.Sprintf("... and %d more\n", len(r.Problems)-room)
+			break
+		}
+		status := fmt.Sprintf("%-10s", p.Status)
+		if p.Status != "changed" && p.Status != "updated" {
+			status = failStyle.Render(status)
+		}
+		s += status + " " + truncateMiddle(displayPath(p.Path), max(m.width-11, 10)) + "\n"
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var headerStyle = lipgloss.NewStyle().Bold(true)
+
+// column is one field of the columnar list view. Columns after the title
+// have a fixed width (with room for the sort marker in the header) and are
+// dropped from the right when the terminal is too narrow to fit them.
+type column struct {
+	name  string
+	width int
+	sort  string
+	value func(m model, video string) string
+}
+
+// minTitleWidth is the narrowest the title column gets before other columns
+// are dropped to make room.
+const minTitleWidth = 20
+
+var columns = []column{
+	{name: "Title", sort: "title", value: func(m model, video string) string {
+		if m.broken[video] != "" {
+			return "⚠ " + videoIcon(video) + m.label(video)
+		}
+		return videoIcon(video) + m.label(video)
+	}},
+	{name: "Year", width: 5, sort: "year", value: func(m model, video string) string {
+		if year := m.year(video); year > 0 {
+			return fmt.Sprint(year)
+		}
+		return ""
+	}},
+	{name: "Score", width: 6, sort: "score", value: func(m model, video string) string {
+		if md := m.index.metadata(video); md != nil && md.Rating > 0 {
+			return fmt.Sprintf("%.1f", md.Rating)
+		}
+		return ""
+	}},
+	{name: "Runtime", width: 8, sort: "runtime", value: func(m model, video string) string {
+		if md := m.index.metadata(video); md != nil && md.Runtime > 0 {
+			return formatRuntime(md.Runtime)
+		}
+		return ""
+	}},
+	{name: "Size", width: 6, sort: "size", value: func(m model, video string) string {
+		if size := m.size(video); size >= 0 {
+			return formatSize(size)
+		}
+		return ""
+	}},
+	{name: "Watched", width: 10, sort: "watched", value: func(m model, video string) string {
+		if t, ok := m.state.Watched[video]; ok {
+			return formatDate(t)
+		}
+		return ""
+	}},
+}
+
+// visibleColumns returns the columns that fit in the terminal width along
+// with the width left for the title.
+func (m model) visibleColumns() ([]column, int) {
+	cols := columns
+	for {
+		title := m.width
+		for _, col := range cols[1:] {
+			title -= col.width + 2
+		}
+		if title >= minTitleWidth || len(cols) == 1 || m.width == 0 {
+			if m.width == 0 {
+				title = 60
+			}
+			return cols, max(title, 10)
+		}
+		cols = cols[:len(cols)-1]
+	}
+}
+
+// columnRow lays out cells under their columns; the title is truncated or
+// padded to titleWidth and the rest are right-aligned.
+func columnRow(cols []column, titleWidth int, cells []string) string {
+	var b strings.Builder
+	title := truncateMiddle(cells[0], titleWidth)
+	b.WriteString(title + strings.Repeat(" ", titleWidth-lipgloss.Width(title)))
+	for i, col := range cols[1:] {
+		cell := truncateMiddle(cells[i+1], col.width)
+		b.WriteString("  " + strings.Repeat(" ", col.width-lipgloss.Width(cell)) + cell)
+	}
+	return b.String()
+}
+
+// viewColumns renders the header and visible rows of the columnar view. The
+// column the list is sorted by is marked in the header.
+func (m model) viewColumns(b *strings.Builder) {
+	cols, titleWidth := m.visibleColumns()
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = tr(col.name)
+		if col.sort == sortModes[m.sortMode] {
+			names[i] += "▾"
+		}
+	}
+	b.WriteString(headerStyle.Render(columnRow(cols, titleWidth, names)) + "\n")
+
+	viewportEnd := min(m.viewportTop+m.viewportSize, len(m.videos))
+	for i := m.viewportTop; i < viewportEnd; i++ {
+		video := m.videos[i]
+		cells := make([]string, len(cols))
+		for j, col := range cols {
+			cells[j] = col.value(m, video)
+		}
+		row := columnRow(cols, titleWidth, cells)
+		if m.cursor == i {
+			row = selectedStyle.Render(row)
+		} else if m.showHidden && m.state.isHidden(video) || isJunk(video) {
+			row = hiddenStyle.Render(row)
+		}
+		b.WriteString(row + "\n")
+	}
+}
+
+// year returns the release year of video from its metadata or file name.
+func (m model) year(video string) int {
+	if md := m.index.metadata(video); md != nil && md.Year > 0 {
+		return md.Year
+	}
+	_, year := cleanTitle(video)
+	return year
+}
+
+// modTime returns the modification time of video in nanoseconds, or 0
+// when it cannot be read. Times are cached for the session like sizes.
+func (m model) modTime(video string) int64 {
+	mtime, ok := m.mtimes[video]
+	if !ok {
+		if info, err := os.Stat(video); err == nil {
+			mtime = info.ModTime().UnixNano()
+		}
+		m.mtimes[video] = mtime
+	}
+	return mtime
+}
+
+// size returns the size of video in bytes, or -1 when it cannot be read
+// (for example URLs from source plugins). Sizes are cached for the session.
+func (m model) size(video string) int64 {
+	size, ok := m.sizes[video]
+	if !ok {
+		size = -1
+		if info, err := os.Stat(video); err == nil {
+			size = info.Size()
+		}
+		m.sizes[video] = size
+	}
+	return size
+}
+
+// formatSize renders a byte count in binary units, e.g. "1.4G", or with
+// size_units = "decimal" in decimal ones, e.g. "1.5GB".
+func formatSize(size int64) string {
+	units := []string{"K", "M", "G", "T", "P", "E"}
+	base := 1024.0
+	if cfg.SizeUnits == "decimal" {
+		units = []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+		base = 1000
+	}
+	if float64(size) < base {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size) / base
+	unit := 0
+	for value >= base && unit < len(units)-1 {
+		value /= base
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, units[unit])
+	}
+	return fmt.Sprintf("%.0f%s", value, units[unit])
+}
+
+// formatDate renders t as a date, "2024-03-09", or with date_format =
+// "relative" as its age, such as "3 days ago".
+func formatDate(t time.Time) string {
+	if cfg.DateFormat == "relative" {
+		return formatAge(time.Since(t))
+	}
+	return t.Format("2006-01-02")
+}
+
+// formatDateTime is formatDate with the time of day added to dates.
+func formatDateTime(t time.Time) string {
+	if cfg.DateFormat == "relative" {
+		return formatAge(time.Since(t))
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// formatAge renders how long ago something happened in at most ten cells,
+// to fit the Watched column.
+func formatAge(age time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case age < time.Minute:
+		return tr("just now")
+	case age < time.Hour:
+		return trf("%d min ago", int(age/time.Minute))
+	case age < day:
+		return trf("%d h ago", int(age/time.Hour))
+	case age < 2*day:
+		return tr("yesterday")
+	case age < 14*day:
+		return trf("%d days ago", int(age/day))
+	case age < 60*day:
+		return trf("%d wk ago", int(age/(7*day)))
+	case age < 365*day:
+		return trf("%d mo ago", int(age/(30*day)))
+	}
+	return trf("%d yr ago", int(age/(365*day)))
+}
+
+// formatRuntime renders a runtime in minutes as "1h 52m".
+func formatRuntime(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
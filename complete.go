@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxCompletions caps the suggestions shown next to the filter input.
+const maxCompletions = 5
+
+// completer suggests endings for the last word of the filter: words from
+// the cleaned-up titles of the library, "tag:" plus the tags in use and
+// "ext:" plus the containers present.
+type completer struct {
+	vocabulary []string
+	// size is the number of videos the vocabulary was built from; it is
+	// rebuilt when more have been scanned since.
+	size int
+	// base is the filter without the word being completed, suggestions
+	// the endings offered for it and current the one Tab last inserted.
+	base        string
+	suggestions []string
+	current     int
+}
+
+func (m *model) buildVocabulary() {
+	seen := map[string]bool{}
+	for _, video := range m.allVideos {
+		for _, word := range strings.Fields(normalizedName(video)) {
+			if len([]rune(word)) >= 3 {
+				seen[word] = true
+			}
+		}
+		if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(video)), "."); ext != "" {
+			seen["ext:"+ext] = true
+		}
+	}
+	for _, e := range m.index.Entries {
+		for _, tag := range e.Tags {
+			seen["tag:"+tag] = true
+		}
+	}
+	vocabulary := make([]string, 0, len(seen))
+	for word := range seen {
+		vocabulary = append(vocabulary, word)
+	}
+	sort.Strings(vocabulary)
+	m.completer.vocabulary = vocabulary
+	m.completer.size = len(m.allVideos)
+}
+
+// suggest finds completions for the last word of the filter input.
+func (m *model) suggest() {
+	c := &m.completer
+	c.suggestions, c.current = nil, -1
+	value := m.searchInput.Value()
+	if value == "" || strings.HasSuffix(value, " ") {
+		return
+	}
+	if c.size != len(m.allVideos) {
+		m.buildVocabulary()
+	}
+	start := strings.LastIndex(value, " ") + 1
+	word := strings.ToLower(value[start:])
+	c.base = value[:start]
+	negated := strings.HasPrefix(word, "!")
+	if negated {
+		c.base += "!"
+		word = word[1:]
+	}
+	if word == "" {
+		return
+	}
+	for i := sort.SearchStrings(c.vocabulary, word); i < len(c.vocabulary) && len(c.suggestions) < maxCompletions; i++ {
+		if !strings.HasPrefix(c.vocabulary[i], word) {
+			break
+		}
+		// Exclusions only apply to plain words, not tag: or ext:.
+		if c.vocabulary[i] != word && !(negated && strings.Contains(c.vocabulary[i], ":")) {
+			c.suggestions = append(c.suggestions, c.vocabulary[i])
+		}
+	}
+}
+
+// complete replaces the word being typed with the next suggestion, so
+// pressing Tab again cycles through them.
+func (m *model) complete() {
+	c := &m.completer
+	if len(c.suggestions) == 0 {
+		return
+	}
+	c.current = (c.current + 1) % len(c.suggestions)
+	m.searchInput.SetValue(c.base + c.suggestions[c.current])
+	m.searchInput.CursorEnd()
+}
+
+// viewSuggestions renders the suggestions shown after the filter input,
+// highlighting the one inserted last.
+func (m model) viewSuggestions() string {
+	var b strings.Builder
+	for i, s := range m.completer.suggestions {
+		b.WriteString("  ")
+		if i == m.completer.current {
+			b.WriteString(selectedStyle.Render(s))
+		} else {
+			b.WriteString(hiddenStyle.Render(s))
+		}
+	}
+	return b.String()
+}
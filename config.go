@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config holds the settings read from config.toml in the config directory.
+type config struct {
+	// PrePlay and PostPlay are shell commands run around playback. They
+	// receive the video's details in VIDEO_* environment variables.
+	PrePlay  string `toml:"pre_play"`
+	PostPlay string `toml:"post_play"`
+	// FollowSymlinks makes the scan descend into symlinked directories.
+	FollowSymlinks bool `toml:"follow_symlinks"`
+	// SkipNetworkMounts skips directories on NFS, SMB and similar mounts.
+	SkipNetworkMounts bool `toml:"skip_network_mounts"`
+	// ScanTimeout gives up on a directory that takes longer to read, e.g. "5s".
+	ScanTimeout duration `toml:"scan_timeout"`
+	// ScanWorkers is how many directories are read at once. Unset, it
+	// depends on whether a root is on a spinning disk, an SSD or a network
+	// share.
+	ScanWorkers int `toml:"scan_workers"`
+	// Refresh decides when the library is walked rather than listed from
+	// the cache of the last walk: "always" (the default), "stale" once the
+	// cache is older than RefreshAfter (default 24h), "background" to show
+	// the cache while walking, or "manual" for --reindex only.
+	Refresh      string   `toml:"refresh"`
+	RefreshAfter duration `toml:"refresh_after"`
+	// Images lists photos next to the videos; mpv shows them as a
+	// slideshow of their folder, ImageDuration apart (default 5s).
+	Images        bool     `toml:"images"`
+	ImageDuration duration `toml:"image_duration"`
+	// WatchedPercent is how much of a video must be played for it to count
+	// as watched (default 90).
+	WatchedPercent float64 `toml:"watched_percent"`
+	// RememberSpeed keeps the playback speed an episode was left at for
+	// the rest of its show.
+	RememberSpeed bool `toml:"remember_speed"`
+	// Window starts the player "fullscreen" or "windowed", and Screen is
+	// the display it opens on (0 is the first) unless another is picked
+	// with "D". Unset, both are left to the player's own configuration.
+	Window string `toml:"window"`
+	Screen *int   `toml:"screen"`
+	// Junk is what happens to samples, trailers and release-group adverts:
+	// "demote" (the default) lists them last, "hide" leaves them out like
+	// hidden files and "off" treats them like any other video. JunkPatterns
+	// replaces the built-in patterns with regular expressions tried against
+	// "folder/name", the file's folder and its name without extension.
+	Junk         string   `toml:"junk"`
+	JunkPatterns []string `toml:"junk_patterns"`
+	junkPatterns []*regexp.Regexp
+	// Accessible runs the interface for screen readers, like --accessible.
+	Accessible bool `toml:"accessible"`
+	// Language is the language of the interface, such as "de", instead
+	// of the one of the locale (LANG). "en" keeps it in English.
+	Language string `toml:"language"`
+	// ProbeFiles has ffprobe read every video after a scan, to warn about
+	// downloads that are broken in ways the quick checks miss.
+	ProbeFiles bool `toml:"probe_files"`
+	// SizeUnits shows file sizes in "binary" units (the default, 1K is
+	// 1024 bytes) or "decimal" ones (1kB is 1000 bytes, as disk makers and
+	// most file managers count). DateFormat shows dates as "absolute" ones
+	// (the default) or "relative" to now, such as "3 days ago".
+	SizeUnits  string `toml:"size_units"`
+	DateFormat string `toml:"date_format"`
+	// Icons puts an icon for the kind of video (movie, episode, disc rip,
+	// image) and a 4K badge before each entry: "nerd" uses Nerd Font
+	// glyphs, "ascii" plain text such as "[TV]", and "off" (the default)
+	// none.
+	Icons string `toml:"icons"`
+	// Loudnorm normalizes the loudness in mpv, for late-night viewing.
+	Loudnorm bool `toml:"loudnorm"`
+	// Players picks another player, or extra arguments, for some folders.
+	Players []playerRule `toml:"player"`
+	// Skip rules seek past intros, end credits and chapters such as
+	// "Opening" during mpv playback.
+	Skip []skipRule `toml:"skip"`
+	// Targets are machines to play on over SSH; DefaultTarget names the one
+	// used unless --target is given.
+	Targets       []target `toml:"target"`
+	DefaultTarget string   `toml:"default_target"`
+	// Syncplay configures watch parties started with "Y".
+	Syncplay syncplayConfig `toml:"syncplay"`
+	// MQTT publishes playback events for home automation.
+	MQTT mqttConfig `toml:"mqtt"`
+	// Transcode lists the ffmpeg presets offered in the action menu, which
+	// write to TranscodeDir unless a preset has its own dir.
+	Transcode    []transcodePreset `toml:"transcode"`
+	TranscodeDir string            `toml:"transcode_dir"`
+	// TMDB looks up trailers on The Movie Database.
+	TMDB tmdbConfig `toml:"tmdb"`
+	// Jellyfin, Plex and Trakt are the accounts watch state is imported
+	// from.
+	Jellyfin jellyfinConfig `toml:"jellyfin"`
+	Plex     plexConfig     `toml:"plex"`
+	Trakt    traktConfig    `toml:"trakt"`
+	// SyncFile is a file in a folder synced between machines (Syncthing,
+	// Dropbox) through which they share ratings, history and watched files.
+	SyncFile string `toml:"sync_file"`
+	// Keys rebinds commands, by mode and command, such as pin = "ctrl+p"
+	// under [keys.list] (see bindings).
+	Keys   map[string]map[string]keyList `toml:"keys"`
+	keymap *keymap
+}
+
+// playerRule is a [[player]] table. Match is a glob (see filepath.Match)
+// tried against the video's path relative to its library root, each folder
+// above it and its file name, so "Anime" covers everything inside that
+// folder and "*.avi" every AVI file. Command defaults to VIDEO_PLAYER.
+type playerRule struct {
+	Match   string   `toml:"match"`
+	Command string   `toml:"command"`
+	Args    []string `toml:"args"`
+}
+
+// matches reports whether the rule applies to video.
+func (r playerRule) matches(video string) bool {
+	return matchesVideo(r.Match, video)
+}
+
+// matchesVideo reports whether the glob pattern of a [[player]] or [[skip]]
+// rule matches video.
+func matchesVideo(pattern, video string) bool {
+	path := video
+	if !filepath.IsAbs(pattern) {
+		root := rootOf(video)
+		if root == "" {
+			return false
+		}
+		rel, err := filepath.Rel(root, video)
+		if err != nil {
+			return false
+		}
+		path = rel
+	}
+	for ; path != "." && path != filepath.Dir(path); path = filepath.Dir(path) {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(video))
+	return ok
+}
+
+// duration is a time.Duration written as a string such as "90s" or "2h".
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+var cfg config
+
+func loadConfig(path string) (config, error) {
+	var c config
+	_, err := toml.DecodeFile(path, &c)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err == nil {
+		switch c.Refresh {
+		case "", "always", "stale", "background", "manual":
+		default:
+			err = fmt.Errorf("refresh: unknown policy %q", c.Refresh)
+		}
+		switch c.Window {
+		case "", "fullscreen", "windowed":
+		default:
+			err = fmt.Errorf("window: want \"fullscreen\" or \"windowed\", not %q", c.Window)
+		}
+		switch c.SizeUnits {
+		case "", "binary", "decimal":
+		default:
+			err = fmt.Errorf("size_units: want \"binary\" or \"decimal\", not %q", c.SizeUnits)
+		}
+		switch c.DateFormat {
+		case "", "absolute", "relative":
+		default:
+			err = fmt.Errorf("date_format: want \"absolute\" or \"relative\", not %q", c.DateFormat)
+		}
+		switch c.Icons {
+		case "", "off", "nerd", "ascii":
+		default:
+			err = fmt.Errorf("icons: want \"nerd\", \"ascii\" or \"off\", not %q", c.Icons)
+		}
+		switch c.Junk {
+		case "", "demote", "hide", "off":
+		default:
+			err = fmt.Errorf("junk: want \"demote\", \"hide\" or \"off\", not %q", c.Junk)
+		}
+		if c.JunkPatterns != nil {
+			c.junkPatterns = []*regexp.Regexp{}
+		}
+		for _, pattern := range c.JunkPatterns {
+			re, reErr := regexp.Compile(pattern)
+			if reErr != nil {
+				err = fmt.Errorf("junk_patterns: %w", reErr)
+				continue
+			}
+			c.junkPatterns = append(c.junkPatterns, re)
+		}
+		var keyErr error
+		if c.keymap, keyErr = newKeymap(c.Keys); keyErr != nil {
+			err = keyErr
+		}
+		for _, rule := range c.Skip {
+			if len(rule.Intro) != 0 && len(rule.Intro) != 2 {
+				err = fmt.Errorf("skip %q: intro needs a start and an end", rule.Match)
+			}
+		}
+	}
+	return c, err
+}
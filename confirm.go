@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// confirmation is a pending yes/no question asked before a destructive
+// action. Answering "always" skips the question for that kind of action in
+// the future; the choice is saved with the profile.
+type confirmation struct {
+	kind   string
+	prompt string
+	run    func(m *model) tea.Cmd
+}
+
+// ask runs the action straight away if the profile always confirms kind,
+// and otherwise shows prompt and waits for an answer.
+func (m model) ask(kind, prompt string, run func(m *model) tea.Cmd) (tea.Model, tea.Cmd) {
+	if m.state.AlwaysConfirm[kind] {
+		cmd := run(&m)
+		return m, cmd
+	}
+	m.confirm = &confirmation{kind: kind, prompt: prompt, run: run}
+	m.status = prompt + " " + keys().help("[%s] yes [%s] no [%s] always", "confirm", "yes", "no", "always")
+	return m, nil
+}
+
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.confirm
+	m.confirm = nil
+	m.status = ""
+	switch keys().command("confirm", msg.String()) {
+	case "yes":
+		cmd := c.run(&m)
+		return m, cmd
+	case "always":
+		m.state.AlwaysConfirm[c.kind] = true
+		if err := m.state.save(); err != nil {
+			m.status = fmt.Sprintf("Error saving preference: %v", err)
+			return m, nil
+		}
+		cmd := c.run(&m)
+		return m, cmd
+	}
+	m.status = "Cancelled"
+	return m, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// controlRequest is sent over the control socket by a second launcher
+// started for the same profile, handing its keywords to this one.
+type controlRequest struct {
+	Keywords []string `json:"keywords"`
+}
+
+type controlResponse struct {
+	OK bool `json:"ok"`
+}
+
+// forwardedMsg delivers a forwarded search to the UI.
+type forwardedMsg controlRequest
+
+// controlSocket is where the running launcher of the active profile listens.
+func controlSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, fmt.Sprintf("movie-launcher-%d-%s.sock", os.Getuid(), profileName))
+}
+
+// forward hands keywords to a launcher already running for the profile.
+// It reports false when there is none to forward to.
+func forward(keywords []string) bool {
+	conn, err := net.DialTimeout("unix", controlSocket(), time.Second)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if err := json.NewEncoder(conn).Encode(controlRequest{Keywords: keywords}); err != nil {
+		return false
+	}
+	var resp controlResponse
+	return json.NewDecoder(conn).Decode(&resp) == nil && resp.OK
+}
+
+// listenControl accepts forwarded searches on the control socket until the
+// returned listener is closed. A socket left behind by a launcher that
+// crashed is replaced.
+func listenControl() (net.Listener, <-chan controlRequest, error) {
+	socket := controlSocket()
+	os.Remove(socket)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	requests := make(chan controlRequest, 8)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handleControl(conn, requests)
+		}
+	}()
+	return l, requests, nil
+}
+
+func handleControl(conn net.Conn, requests chan<- controlRequest) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	var req controlRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		logger.Warn("bad control request", "err", err)
+		return
+	}
+	logger.Info("search forwarded", "keywords", req.Keywords)
+	select {
+	case requests <- req:
+		json.NewEncoder(conn).Encode(controlResponse{OK: true})
+	default:
+		json.NewEncoder(conn).Encode(controlResponse{OK: false})
+	}
+}
+
+func waitForForward(ch <-chan controlRequest) tea.Cmd {
+	return func() tea.Msg {
+		req, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return forwardedMsg(req)
+	}
+}
+
+// applyForwarded shows the results of a forwarded search by making its
+// keywords the filter.
+func (m *model) applyForwarded(msg forwardedMsg) {
+	var words []string
+	for _, word := range msg.Keywords {
+		if strings.ContainsRune(word, ' ') {
+			word = `"` + word + `"`
+		}
+		words = append(words, word)
+	}
+	m.filter = strings.Join(words, " ")
+	m.searchInput.SetValue(m.filter)
+	m.dashboard = false
+	m.nowPlaying = false
+	m.refresh()
+	m.status = "Search from another launcher: " + m.filter
+}
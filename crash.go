@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashReport is the first panic caught while the interface runs, with the
+// stack of the goroutine that panicked.
+type crashReport struct {
+	value any
+	stack []byte
+}
+
+// crash holds the program being run, which a caught panic stops, and the
+// report of that panic.
+var crash struct {
+	mu      sync.Mutex
+	program *tea.Program
+	report  *crashReport
+}
+
+// crashSafe wraps the model so that a panic in Update, View or a command
+// stops the program the normal way, which takes the terminal out of the
+// alternate screen, rather than bubbletea's way of printing the panic over
+// the interface. main then reports it with reportCrash.
+type crashSafe struct {
+	model tea.Model
+}
+
+func (c crashSafe) Init() tea.Cmd {
+	defer catchPanic()
+	return safeCmd(c.model.Init())
+}
+
+func (c crashSafe) Update(msg tea.Msg) (next tea.Model, cmd tea.Cmd) {
+	// After a panic the model stays as it was while the program stops.
+	next = c
+	defer catchPanic()
+	m, cmd := c.model.Update(msg)
+	return crashSafe{m}, safeCmd(cmd)
+}
+
+func (c crashSafe) View() string {
+	defer catchPanic()
+	return c.model.View()
+}
+
+// safeCmd wraps cmd, and the commands of the batches it returns, so a panic
+// while it runs is caught too.
+func safeCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer catchPanic()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = safeCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
+
+// catchPanic, deferred, recovers a panic and has the running program stop
+// so main can report it. Without a program, such as in a subcommand, the
+// panic goes on as usual.
+func catchPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	crash.mu.Lock()
+	defer crash.mu.Unlock()
+	if crash.program == nil {
+		panic(r)
+	}
+	if crash.report == nil {
+		crash.report = &crashReport{value: r, stack: debug.Stack()}
+		// Kill waits for the event loop, which may be the goroutine that
+		// panicked.
+		go crash.program.Kill()
+	}
+}
+
+// runSafely runs the interface for initial, returning its final model and
+// the panic that stopped it, if any.
+func runSafely(initial model, opts ...tea.ProgramOption) (model, *crashReport, error) {
+	p := tea.NewProgram(crashSafe{initial}, opts...)
+	crash.mu.Lock()
+	crash.program = p
+	crash.mu.Unlock()
+	final, err := p.Run()
+
+	crash.mu.Lock()
+	defer crash.mu.Unlock()
+	crash.program = nil
+	if report := crash.report; report != nil {
+		crash.report = nil
+		return initial, report, nil
+	}
+	return final.(crashSafe).model.(model), nil, err
+}
+
+// reportCrash writes a crash log to the data directory and tells the user
+// where it is.
+func reportCrash(report *crashReport) {
+	fmt.Fprintf(os.Stderr, "movie-launcher crashed: %v\n", report.value)
+	path := filepath.Join(dataDir(), "crash-"+time.Now().Format("20060102-150405")+".log")
+	log := fmt.Sprintf("movie-launcher crashed at %s\n\npanic: %v\n\n%s", time.Now().Format(time.RFC3339), report.value, report.stack)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err == nil {
+		err = os.WriteFile(path, []byte(log), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\nCould not write the crash log: %v\n", report.stack, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "This is a bug. The details are in %s; please include it when reporting the problem.\n", path)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dashboardSize caps the number of videos listed per dashboard section.
+const dashboardSize = 8
+
+var sectionStyle = lipgloss.NewStyle().Bold(true).Underline(true)
+
+type dashboardSection struct {
+	title  string
+	videos []string
+}
+
+// buildDashboard assembles the home screen shown when no keywords are given.
+// The pinned videos lead it, when there are any.
+func buildDashboard(videos []string, st *state, ix *index) []dashboardSection {
+	available := make(map[string]bool, len(videos))
+	var pinned []string
+	for _, video := range videos {
+		available[video] = true
+		if st.isPinned(video) && len(pinned) < dashboardSize {
+			pinned = append(pinned, video)
+		}
+	}
+
+	var continueWatching []string
+	seen := map[string]bool{}
+	for _, h := range st.History {
+		if seen[h.Path] || !available[h.Path] {
+			continue
+		}
+		seen[h.Path] = true
+		if hasResume(h.Path) && len(continueWatching) < dashboardSize {
+			continueWatching = append(continueWatching, h.Path)
+		}
+	}
+
+	var upNext []string
+	for _, video := range nextUp(videos, st, ix) {
+		if !seen[video] && len(upNext) < dashboardSize {
+			upNext = append(upNext, video)
+		}
+	}
+
+	var sections []dashboardSection
+	if len(pinned) > 0 {
+		sections = append(sections, dashboardSection{title: "Pinned", videos: pinned})
+	}
+	return append(sections,
+		dashboardSection{title: "Continue watching", videos: continueWatching},
+		dashboardSection{title: "Next up", videos: upNext},
+		dashboardSection{title: "Recently added", videos: recentlyAdded(videos, dashboardSize)},
+	)
+}
+
+// recentlyAdded returns up to n videos ordered by newest modification time.
+func recentlyAdded(videos []string, n int) []string {
+	type dated struct {
+		path  string
+		mtime int64
+	}
+	var files []dated
+	for _, video := range videos {
+		if info, err := os.Stat(video); err == nil {
+			files = append(files, dated{video, info.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime > files[j].mtime })
+	var recent []string
+	for i := 0; i < len(files) && i < n; i++ {
+		recent = append(recent, files[i].path)
+	}
+	return recent
+}
+
+// dashboardVideos flattens the sections in display order for cursor movement.
+func (m model) dashboardVideos() []string {
+	var videos []string
+	for _, section := range m.sections {
+		videos = append(videos, section.videos...)
+	}
+	return videos
+}
+
+func (m model) updateDashboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	videos := m.dashboardVideos()
+	switch keys().command("dashboard", msg.String()) {
+	case "quit":
+		return m.quit()
+	case "list":
+		m.dashboard = false
+	case "now-playing":
+		if m.playing != nil && m.playing.client != nil {
+			m.nowPlaying = true
+		}
+	case "up":
+		if m.dashCursor > 0 {
+			m.dashCursor--
+		}
+	case "down":
+		if m.dashCursor < len(videos)-1 {
+			m.dashCursor++
+		}
+	case "play":
+		if len(videos) > 0 {
+			return m.playEdition(videos[m.dashCursor])
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewDashboard() string {
+	s := keys().help("Home - %s/%s, %s to play, %s for the full list, %s to quit", "dashboard", "up", "down", "play", "list", "quit") + "\n"
+	s += trf("%d videos in library", len(m.allVideos))
+	if m.scanning {
+		s += " - " + m.scanStatus()
+	}
+	s += "\n"
+	i := 0
+	for _, section := range m.sections {
+		s += "\n" + sectionStyle.Render(tr(section.title)) + "\n"
+		if len(section.videos) == 0 {
+			s += hiddenStyle.Render(tr("nothing here yet")) + "\n"
+		}
+		for _, video := range section.videos {
+			label := truncateMiddle(videoIcon(video)+m.label(video), m.width)
+			if i == m.dashCursor {
+				s += selectedStyle.Render(label) + "\n"
+			} else {
+				s += label + "\n"
+			}
+			i++
+		}
+	}
+	return s
+}
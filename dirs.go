@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directories set with --data-dir, --cache-dir and --config-dir, used
+// instead of the XDG base directories.
+var (
+	dataDirFlag   string
+	cacheDirFlag  string
+	configDirFlag string
+)
+
+// xdgDir returns the movie-launcher directory below the base directory named
+// by env, or below fallback in the home directory when env is unset. As the
+// XDG spec asks, relative values of env are ignored.
+func xdgDir(override, env string, fallback ...string) string {
+	if override != "" {
+		return override
+	}
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "movie-launcher")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".movie-launcher"
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), "movie-launcher")...)
+}
+
+// dataDir returns the directory used for persistent launcher data: the
+// index, profiles and logs.
+func dataDir() string {
+	return xdgDir(dataDirFlag, "XDG_DATA_HOME", ".local", "share")
+}
+
+// cacheDir returns the directory for data that can be rebuilt, such as the
+// cached library file list.
+func cacheDir() string {
+	return xdgDir(cacheDirFlag, "XDG_CACHE_HOME", ".cache")
+}
+
+// configDir returns the directory holding config.toml and plugins.
+func configDir() string {
+	return xdgDir(configDirFlag, "XDG_CONFIG_HOME", ".config")
+}
+
+// expandHome replaces a leading "~/" in a configured path with the home
+// directory.
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
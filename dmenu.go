@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runDmenu prints one cleaned title per matching video for dmenu, rofi or
+// fuzzel, then plays the video whose title comes back on stdin. Titles that
+// occur more than once get their path appended to tell them apart.
+func runDmenu(ctx context.Context, keywords []string, ix *index, st *state, settings profileSettings) error {
+	if settings.locked() {
+		return errors.New("the profile is locked with a PIN")
+	}
+	found, err := searchVideos(ctx, keywords, ix, st)
+	if err != nil {
+		return err
+	}
+	var videos []string
+	seen := map[string]int{}
+	for _, video := range found {
+		if settings.allows(video, ix) && !st.isHidden(video) {
+			videos = append(videos, video)
+			seen[videoTitle(video, ix)]++
+		}
+	}
+
+	byTitle := make(map[string]string, len(videos))
+	out := bufio.NewWriter(os.Stdout)
+	for _, video := range videos {
+		title := videoTitle(video, ix)
+		if seen[title] > 1 {
+			title += " - " + displayPath(video)
+		}
+		byTitle[title] = video
+		fmt.Fprintln(out, title)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	// Closing stdout tells the menu the list is complete.
+	os.Stdout.Close()
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		// Nothing picked, e.g. the menu was dismissed.
+		return nil
+	}
+	video, ok := byTitle[line]
+	if !ok {
+		return fmt.Errorf("no video titled %q", line)
+	}
+	return playAnnounced(video, videos, ix, st, playerCommand)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// editionPattern matches what tells the editions of a film apart in file
+// names: the cut, and the resolution and kind of the release.
+var editionPattern = regexp.MustCompile(`(?i)\b(director'?s cut|extended(?: cut| edition)?|theatrical(?: cut| edition)?|final cut|special edition|ultimate (?:cut|edition)|unrated|remastered|imax|criterion|2160p|4k|uhd|1080p|720p|480p|remux|hdr)\b`)
+
+// editionOf returns what the editions of a film have in common: the
+// library root it is under, its title and year without the name of the
+// edition. It is "" for episodes, which have no editions, images and videos
+// of no known year, whose titles are too often shared by unrelated files.
+func editionOf(video string, ix *index) string {
+	if _, ok := parseEpisode(video); ok || isImageFile(video) {
+		return ""
+	}
+	title, year := titleYear(video, ix)
+	title = matchKey(editionPattern.ReplaceAllString(title, " "))
+	if title == "" || year == 0 {
+		return ""
+	}
+	scope := rootOf(video)
+	if scope == "" {
+		scope = filepath.Dir(video)
+	}
+	return fmt.Sprintf("%s|%s|%d", scope, title, year)
+}
+
+// editionCache is editionOf a video, with the metadata it was worked out
+// from.
+type editionCache struct {
+	key string
+	md  *metadata
+}
+
+// editionKey returns editionOf video, cached until its metadata changes.
+func (m model) editionKey(video string) string {
+	md := m.index.metadata(video)
+	cached, ok := m.editionKeys[video]
+	if !ok || cached.md != md {
+		cached = editionCache{editionOf(video, m.index), md}
+		m.editionKeys[video] = cached
+	}
+	return cached.key
+}
+
+// editionsOf finds the films among videos found in several editions, such
+// as a director's cut and a 4K remux, and returns them in natural order of
+// path by the first, which stands for the others in the list. Files are only
+// taken for editions when their names tell each apart (see editionName).
+func (m model) editionsOf(videos []string) map[string][]string {
+	films := map[string][]string{}
+	for _, video := range videos {
+		if key := m.editionKey(video); key != "" {
+			films[key] = append(films[key], video)
+		}
+	}
+	editions := map[string][]string{}
+	for _, videos := range films {
+		if len(videos) < 2 || !namedApart(videos) {
+			continue
+		}
+		slices.SortFunc(videos, naturalCompare)
+		editions[videos[0]] = videos
+	}
+	return editions
+}
+
+// namedApart reports whether no two of videos have the same edition name.
+func namedApart(videos []string) bool {
+	names := map[string]bool{}
+	for _, video := range videos {
+		name := matchKey(editionName(video))
+		if names[name] {
+			return false
+		}
+		names[name] = true
+	}
+	return true
+}
+
+// addsEdition reports whether any of added, just added to allVideos, is
+// another edition of a film listed there.
+func (m model) addsEdition(added []string) bool {
+	films := map[string]int{}
+	for _, video := range m.allVideos {
+		if key := m.editionKey(video); key != "" {
+			films[key]++
+		}
+	}
+	return slices.ContainsFunc(added, func(video string) bool { return films[m.editionKey(video)] > 1 })
+}
+
+// editionName names the edition video is, such as "Director's Cut
+// 1080p", from its file name, or "" when the name says nothing of it.
+func editionName(video string) string {
+	name := strings.NewReplacer(".", " ", "_", " ").Replace(filepath.Base(video))
+	var found []string
+	for _, match := range editionPattern.FindAllString(name, -1) {
+		if !slices.ContainsFunc(found, func(s string) bool { return strings.EqualFold(s, match) }) {
+			found = append(found, match)
+		}
+	}
+	return strings.Join(found, " ")
+}
+
+// playEdition plays video, first asking which edition to play when the
+// film has several.
+func (m model) playEdition(video string) (tea.Model, tea.Cmd) {
+	if len(m.editions[video]) < 2 {
+		return m.play(video)
+	}
+	m.editionMode = true
+	m.editionVideo = video
+	m.editionCursor = 0
+	return m, nil
+}
+
+func (m model) updateEditions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	editions := m.editions[m.editionVideo]
+	switch keys().command("editions", msg.String()) {
+	case "up":
+		m.editionCursor = max(m.editionCursor-1, 0)
+	case "down":
+		m.editionCursor = max(min(m.editionCursor+1, len(editions)-1), 0)
+	case "play":
+		m.editionMode = false
+		if m.editionCursor < len(editions) {
+			return m.play(editions[m.editionCursor])
+		}
+	case "close":
+		m.editionMode = false
+	}
+	return m, nil
+}
+
+// editionLine describes the edition video in the edition picker: its name,
+// file name and size.
+func (m model) editionLine(video string) string {
+	line := filepath.Base(video)
+	if name := editionName(video); name != "" {
+		line = name + "  " + hiddenStyle.Render(line)
+	}
+	if size := m.size(video); size >= 0 {
+		line += "  " + hiddenStyle.Render(formatSize(size))
+	}
+	return line
+}
+
+func (m model) viewEditions() string {
+	var b strings.Builder
+	b.WriteString(m.fit(keys().help("Editions - %s to play one, %s to cancel", "editions", "play", "close")) + "\n")
+	b.WriteString(m.fit(crumbStyle.Render(videoTitle(m.editionVideo, m.index))) + "\n\n")
+	for i, video := range m.editions[m.editionVideo] {
+		line := m.fit(m.editionLine(video))
+		if i == m.editionCursor {
+			// Unstyled, so the selection covers the whole line.
+			line = selectedStyle.Render(ansi.Strip(line))
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestEditionsOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		videos []string
+		want   int
+	}{
+		{"cuts", []string{"/f/Blade Runner (1982).mkv", "/f/Blade.Runner.1982.Directors.Cut.mkv"}, 2},
+		{"releases", []string{"/f/Heat.1995.1080p.mkv", "/f/Heat.1995.2160p.Remux.mkv"}, 2},
+		{"same name", []string{"/f/a/Heat.1995.mkv", "/f/b/Heat.1995.mkv"}, 0},
+		{"same edition", []string{"/f/a/Heat.1995.1080p.mkv", "/f/b/Heat.1995.1080p.mkv"}, 0},
+		{"no year", []string{"/f/a/Extras/trailer.mkv", "/f/b/Extras/trailer.1080p.mkv"}, 0},
+		{"images", []string{"/f/Poster.1995.jpg", "/f/Poster.1995.4K.jpg"}, 0},
+		{"episodes", []string{"/f/Show.S01E01.720p.mkv", "/f/Show.S01E01.1080p.mkv"}, 0},
+		{"different years", []string{"/f/Dune (1984).mkv", "/f/Dune (2021) 2160p.mkv"}, 0},
+	}
+	for _, tt := range tests {
+		m := model{index: &index{Entries: map[string]*indexEntry{}}, editionKeys: map[string]editionCache{}}
+		editions := m.editionsOf(tt.videos)
+		got := 0
+		for _, group := range editions {
+			got += len(group)
+		}
+		if got != tt.want {
+			t.Errorf("%s: editionsOf(%q) grouped %d videos, want %d", tt.name, tt.videos, got, tt.want)
+		}
+	}
+}
+
+func TestEditionsOfScope(t *testing.T) {
+	defer func(dirs []string) { videoDirs = dirs }(videoDirs)
+	videoDirs = []string{"/films", "/clips"}
+	m := model{index: &index{Entries: map[string]*indexEntry{}}, editionKeys: map[string]editionCache{}}
+	if editions := m.editionsOf([]string{"/films/Heat.1995.mkv", "/clips/Heat.1995.720p.mkv"}); len(editions) != 0 {
+		t.Errorf("editionsOf grouped videos from different roots: %q", editions)
+	}
+	if editions := m.editionsOf([]string{"/films/a/Heat.1995.mkv", "/films/b/Heat.1995.720p.mkv"}); len(editions) != 1 {
+		t.Errorf("editionsOf did not group editions in one root: %q", editions)
+	}
+}
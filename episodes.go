@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// episodePattern matches "S01E02" and "1x02" style episode markers.
+	episodePattern = regexp.MustCompile(`(?i)(?:s(\d{1,2})[ ._-]?e(\d{1,3})|\b(\d{1,2})x(\d{2,3})\b)`)
+	// absolutePattern matches anime-style names such as "[Group] Title -
+	// 013 [1080p]", numbered from the show's first episode on rather than
+	// within seasons.
+	absolutePattern = regexp.MustCompile(`^\[[^\]]*\]\s*(.+?)\s+-\s+(\d{1,4})(?:v\d)?(?:\s*[\[(].*)?$`)
+	seasonDir       = regexp.MustCompile(`(?i)^(season|series|s)[ ._-]?\d+$`)
+	separators      = strings.NewReplacer(".", " ", "_", " ", "-", " ")
+)
+
+type episode struct {
+	path    string
+	show    string
+	season  int
+	episode int
+	// absolute is the number of the episode counted from the show's first,
+	// for anime-style names, or 0.
+	absolute int
+	// aired is the date the episode first aired, as scraped, such as
+	// "2009-04-05".
+	aired string
+}
+
+// parseEpisode extracts the show name and episode numbers from a file path.
+// When the file name has nothing before the episode marker, the show is
+// named after its folder (skipping "Season N" folders). A show set in a
+// folder's .movie-launcher.toml takes precedence, and also covers files
+// without a marker.
+func parseEpisode(path string) (episode, bool) {
+	o := overrideFor(path)
+	ep, ok := parseEpisodeName(path)
+	if o.Show == "" {
+		return ep, ok
+	}
+	if !ok {
+		n := folderPosition(path, o.Order)
+		if n == 0 {
+			return episode{}, false
+		}
+		ep = episode{path: path, season: max(o.Season, 1), episode: n}
+	}
+	ep.show = showKey(o.Show)
+	return ep, true
+}
+
+// parseEpisodeName does the work of parseEpisode from the path alone.
+func parseEpisodeName(path string) (episode, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	loc := episodePattern.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return parseAbsolute(path, name)
+	}
+	match := episodePattern.FindStringSubmatch(name)
+	ep := episode{path: path}
+	if match[1] != "" {
+		ep.season, _ = strconv.Atoi(match[1])
+		ep.episode, _ = strconv.Atoi(match[2])
+	} else {
+		ep.season, _ = strconv.Atoi(match[3])
+		ep.episode, _ = strconv.Atoi(match[4])
+	}
+
+	// Release groups, as in "[Group] Show S01E02", are not part of the show.
+	ep.show = showKey(bracketed.ReplaceAllString(name[:loc[0]], " "))
+	if ep.show == "" {
+		dir := filepath.Dir(path)
+		if seasonDir.MatchString(filepath.Base(dir)) {
+			dir = filepath.Dir(dir)
+		}
+		ep.show = showKey(filepath.Base(dir))
+	}
+	return ep, ep.show != ""
+}
+
+// parseAbsolute parses name, the file name of path, as an anime-style
+// absolute-numbered episode. It counts as season 1 until the scrapers map
+// it onto a season of the show's guide (see withScraped).
+func parseAbsolute(path, name string) (episode, bool) {
+	match := absolutePattern.FindStringSubmatch(strings.ReplaceAll(name, "_", " "))
+	if match == nil {
+		return episode{}, false
+	}
+	n, _ := strconv.Atoi(match[2])
+	ep := episode{path: path, show: showKey(match[1]), season: 1, episode: n, absolute: n}
+	return ep, ep.show != "" && n > 0
+}
+
+func showKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(separators.Replace(s)), " "))
+}
+
+// showEpisodes groups the episodes among videos by show, in episode order
+// (see compareEpisodes).
+func showEpisodes(videos []string, ix *index) map[string][]episode {
+	shows := map[string][]episode{}
+	for _, video := range videos {
+		if ep, ok := parseEpisode(video); ok {
+			shows[ep.show] = append(shows[ep.show], withScraped(ep, ix))
+		}
+	}
+	for _, eps := range shows {
+		byAired := airedShows(eps)[eps[0].show]
+		slices.SortFunc(eps, func(a, b episode) int { return compareEpisodes(a, b, byAired) })
+	}
+	return shows
+}
+
+// withScraped returns ep with the season and episode numbers the scrapers
+// found for it, which beat those of file names for absolute-numbered
+// releases and specials, and the date it aired.
+func withScraped(ep episode, ix *index) episode {
+	md := ix.metadata(ep.path)
+	if md == nil {
+		return ep
+	}
+	if md.Episode > 0 {
+		ep.season, ep.episode = md.Season, md.Episode
+	}
+	if _, err := time.Parse(time.DateOnly, md.Aired); err == nil {
+		ep.aired = md.Aired
+	}
+	return ep
+}
+
+// airedShows returns the shows among eps whose every episode has an air
+// date, which are ordered by it.
+func airedShows(eps []episode) map[string]bool {
+	shows := map[string]bool{}
+	for _, ep := range eps {
+		aired, seen := shows[ep.show]
+		shows[ep.show] = (aired || !seen) && ep.aired != ""
+	}
+	return shows
+}
+
+// compareEpisodes orders the episodes a and b of a show by season and
+// episode, or first by air date when byAired, which puts specials between
+// the episodes they aired among.
+func compareEpisodes(a, b episode, byAired bool) int {
+	if byAired {
+		if c := strings.Compare(a.aired, b.aired); c != 0 {
+			return c
+		}
+	}
+	return cmp.Or(cmp.Compare(a.season, b.season), cmp.Compare(a.episode, b.episode))
+}
+
+// nextEpisode returns the first unwatched episode after the last watched one,
+// along with when that last episode was watched. The time is zero when
+// nothing of the show has been watched yet, in which case the first
+// unwatched episode is returned.
+func nextEpisode(eps []episode, st *state) (string, time.Time) {
+	last := -1
+	var lastWatched time.Time
+	for i, ep := range eps {
+		if t, ok := st.Watched[ep.path]; ok {
+			last = i
+			if t.After(lastWatched) {
+				lastWatched = t
+			}
+		}
+	}
+	for _, ep := range eps[last+1:] {
+		if _, ok := st.Watched[ep.path]; !ok {
+			return ep.path, lastWatched
+		}
+	}
+	return "", lastWatched
+}
+
+// nextUp returns the next unwatched episode of every show that has at least
+// one watched episode, most recently watched show first.
+func nextUp(videos []string, st *state, ix *index) []string {
+	type candidate struct {
+		path    string
+		watched time.Time
+	}
+	var next []candidate
+	for _, eps := range showEpisodes(videos, ix) {
+		if path, watched := nextEpisode(eps, st); path != "" && !watched.IsZero() {
+			next = append(next, candidate{path, watched})
+		}
+	}
+
+	sort.Slice(next, func(i, j int) bool { return next[i].watched.After(next[j].watched) })
+	paths := make([]string, len(next))
+	for i, c := range next {
+		paths[i] = c.path
+	}
+	return paths
+}
+
+// nextUpFor returns the episode to continue the show video belongs to.
+func nextUpFor(video string, videos []string, st *state, ix *index) (string, bool) {
+	ep, ok := parseEpisode(video)
+	if !ok {
+		return "", false
+	}
+	next, _ := nextEpisode(showEpisodes(videos, ix)[ep.show], st)
+	return next, next != ""
+}
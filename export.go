@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFile is the portable watch state written by "export" and read back
+// by "import" on another machine. Items are matched by title and year, or
+// show and episode, since paths differ between machines.
+type exportFile struct {
+	Version  int          `json:"version"`
+	Exported time.Time    `json:"exported"`
+	Profile  string       `json:"profile"`
+	Items    []exportItem `json:"items"`
+}
+
+type exportItem struct {
+	Title   string `json:"title,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	Show    string `json:"show,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+	// Path is relative to the library root, for reference.
+	Path     string    `json:"path"`
+	Watched  time.Time `json:"watched,omitzero"`
+	Stars    int       `json:"stars,omitempty"`
+	Position float64   `json:"position,omitempty"` // seconds
+}
+
+// exportItems describes every video the profile watched, rated or stopped
+// part way through.
+func exportItems(ix *index, st *state) []exportItem {
+	paths := map[string]bool{}
+	for path := range st.Watched {
+		paths[path] = true
+	}
+	for path := range st.Ratings {
+		paths[path] = true
+	}
+	for _, h := range st.History {
+		paths[h.Path] = true
+	}
+	var items []exportItem
+	for path := range paths {
+		item := exportItem{Path: displayPath(path), Watched: st.Watched[path], Stars: st.Ratings[path]}
+		if item.Watched.IsZero() {
+			item.Position = resumePosition(path)
+		}
+		if item.Watched.IsZero() && item.Stars == 0 && item.Position == 0 {
+			continue
+		}
+		if ep, ok := parseEpisode(path); ok {
+			ep = withScraped(ep, ix)
+			item.Show, item.Season, item.Episode = ep.show, ep.season, ep.episode
+		} else {
+			item.Title, item.Year = titleYear(path, ix)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items
+}
+
+// resumePosition reads the position mpv saved for video, in seconds.
+func resumePosition(video string) float64 {
+	f, err := os.Open(filepath.Join(watchLaterDir(), watchLaterName(video)))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "start="); ok {
+			seconds, _ := strconv.ParseFloat(v, 64)
+			return seconds
+		}
+	}
+	return 0
+}
+
+// fileRecords reads a file written by "export".
+func fileRecords(path string) ([]watchRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f exportFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var records []watchRecord
+	for _, item := range f.Items {
+		records = append(records, watchRecord{
+			title: item.Title, year: item.Year,
+			show: item.Show, season: item.Season, episode: item.Episode,
+			watched: item.Watched, position: item.Position, stars: item.Stars,
+		})
+	}
+	return records, nil
+}
+
+// exportWatchState is the "export" subcommand: it sends the profile's
+// watched videos and ratings to Trakt, or writes them to a JSON file ("-"
+// for stdout) that "import" reads on another machine.
+func exportWatchState(ctx context.Context, args []string, ix *index, st *state) error {
+	if len(args) != 1 {
+		return errors.New("usage: movie-launcher export trakt|<file.json>|-")
+	}
+	items := exportItems(ix, st)
+	if args[0] == "trakt" {
+		added, err := traktExport(ctx, cfg.Trakt, items)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sent %d watched videos and %d ratings to Trakt\n", added.watched, added.ratings)
+		return nil
+	}
+	data, err := json.MarshalIndent(exportFile{Version: 1, Exported: time.Now(), Profile: profileName, Items: items}, "", "  ")
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := io.Copy(out, bytes.NewReader(append(data, '\n'))); err != nil {
+		return err
+	}
+	if args[0] != "-" {
+		fmt.Printf("Exported %d videos to %s\n", len(items), args[0])
+	}
+	return nil
+}
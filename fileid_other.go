@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+// fileID identifies a directory independently of the path used to reach it.
+type fileID string
+
+func dirID(path string) (fileID, error) {
+	return realPathID(path)
+}
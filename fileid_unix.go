@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID identifies a directory independently of the path used to reach it.
+type fileID string
+
+func dirID(path string) (fileID, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileID(fmt.Sprintf("%d:%d", st.Dev, st.Ino)), nil
+	}
+	return realPathID(path)
+}
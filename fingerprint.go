@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fingerprintChunk is how much of the start and of the end of a file is
+// hashed into its fingerprint.
+const fingerprintChunk = 64 << 10
+
+// fingerprint identifies the content of a file cheaply, from its size and a
+// hash of its first and last 64KB, so that it can be recognised after being
+// renamed or moved. It looks like "1234567:ab12...".
+func fingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, fingerprintChunk); err != nil && err != io.EOF {
+		return "", err
+	}
+	if tail := info.Size() - fingerprintChunk; tail > fingerprintChunk {
+		if _, err := io.Copy(h, io.NewSectionReader(f, tail, fingerprintChunk)); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d:%s", info.Size(), hex.EncodeToString(h.Sum(nil)[:16])), nil
+}
+
+// fingerprintSize returns the file size recorded in a fingerprint.
+func fingerprintSize(fp string) int64 {
+	size, _ := strconv.ParseInt(strings.SplitN(fp, ":", 2)[0], 10, 64)
+	return size
+}
+
+// track records the fingerprint of path unless it already has one. Videos
+// get one once they have personal data, such as a rating or a place in the
+// watch history, that should follow them when moved.
+func (ix *index) track(path string) {
+	if !filepath.IsAbs(path) {
+		return
+	}
+	if e := ix.entry(path); e != nil && e.Fingerprint != "" {
+		return
+	}
+	fp, err := fingerprint(path)
+	if err != nil {
+		logger.Debug("fingerprint failed", "path", path, "err", err)
+		return
+	}
+	ix.ensure(path).Fingerprint = fp
+}
+
+// movesMsg reports fingerprints computed in the background and the videos
+// found to be moved, from their old path to the new one.
+type movesMsg struct {
+	fingerprints map[string]string
+	moves        map[string]string
+}
+
+// findMoves looks for fingerprinted files that disappeared among videos,
+// the results of a scan, in the background. Only videos with the size of a
+// missing file are hashed. Videos with personal data but no fingerprint yet
+// are fingerprinted along the way.
+func (m model) findMoves(videos []string) tea.Cmd {
+	missing := map[string]string{}
+	sizes := map[int64]bool{}
+	for path, e := range m.index.Entries {
+		if e.Fingerprint != "" && missingReason(path) != "" {
+			missing[e.Fingerprint] = path
+			sizes[fingerprintSize(e.Fingerprint)] = true
+		}
+	}
+	var candidates, untracked []string
+	for _, video := range videos {
+		e := m.index.entry(video)
+		switch {
+		case e != nil && e.Fingerprint != "" || !filepath.IsAbs(video):
+		case m.state.hasData(video):
+			untracked = append(untracked, video)
+		case e == nil && len(missing) > 0:
+			candidates = append(candidates, video)
+		}
+	}
+	if len(untracked) == 0 && len(candidates) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		msg := movesMsg{fingerprints: map[string]string{}, moves: map[string]string{}}
+		for _, video := range untracked {
+			if fp, err := fingerprint(video); err == nil {
+				msg.fingerprints[video] = fp
+			}
+		}
+		for _, video := range candidates {
+			info, err := os.Stat(video)
+			if err != nil || !sizes[info.Size()] {
+				continue
+			}
+			fp, err := fingerprint(video)
+			if err != nil {
+				continue
+			}
+			if old, ok := missing[fp]; ok {
+				msg.moves[old] = video
+				msg.fingerprints[video] = fp
+				delete(missing, fp)
+			}
+		}
+		return msg
+	}
+}
+
+// applyMoves stores the fingerprints in msg and moves the index entry,
+// ratings, history and resume positions of every profile from the old path
+// of each moved video to the new one.
+func (m *model) applyMoves(msg movesMsg) {
+	for old, video := range msg.moves {
+		logger.Info("file moved", "from", old, "to", video)
+		m.index.Entries[video] = m.index.Entries[old]
+		delete(m.index.Entries, old)
+	}
+	for video, fp := range msg.fingerprints {
+		m.index.ensure(video).Fingerprint = fp
+	}
+	if err := m.index.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving index: %v", err)
+		return
+	}
+	if len(msg.moves) == 0 {
+		return
+	}
+	if err := moveProfileData(msg.moves, m.state); err != nil {
+		m.status = fmt.Sprintf("Error moving watch data: %v", err)
+		return
+	}
+	m.status = fmt.Sprintf("Followed %d moved files", len(msg.moves))
+}
+
+// moveProfileData renames the moved files in the state and watch-later
+// directory of every profile. current is the loaded state of the active
+// profile, which is updated in place.
+func moveProfileData(moves map[string]string, current *state) error {
+	dirs := []string{filepath.Dir(current.path)}
+	profiles, err := os.ReadDir(filepath.Join(dataDir(), "profiles"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, p := range profiles {
+		if dir := filepath.Join(dataDir(), "profiles", p.Name()); dir != dirs[0] && p.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	for i, dir := range dirs {
+		st := current
+		if i > 0 {
+			if st, err = loadState(filepath.Join(dir, "state.json")); err != nil {
+				return err
+			}
+		}
+		for old, video := range moves {
+			st.rename(old, video)
+			// A missing resume position is fine; there was nothing to move.
+			os.Rename(filepath.Join(dir, "watch_later", watchLaterName(old)),
+				filepath.Join(dir, "watch_later", watchLaterName(video)))
+		}
+		if err := st.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
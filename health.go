@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// healthTimeout bounds how long a root may take to answer before the health
+// view reports it unreachable, unless scan_timeout is shorter.
+const healthTimeout = 5 * time.Second
+
+var (
+	okStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	failStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// rootHealth is the state of one library root as checked by checkRoots.
+type rootHealth struct {
+	root    string
+	err     error
+	network bool
+	latency time.Duration
+	// indexed and files come from the cached file list; indexed is zero
+	// when the root has not been scanned completely yet.
+	indexed time.Time
+	files   int
+}
+
+type healthMsg []rootHealth
+
+// checkRoots reads the top of every library root in the background and
+// reports whether it answered, how fast, and what the library cache knows
+// about it.
+func checkRoots(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		opts := walkOptions{timeout: healthTimeout, ctx: ctx}
+		if t := cfg.ScanTimeout.Duration; t > 0 && t < opts.timeout {
+			opts.timeout = t
+		}
+		cache := loadLibraryCache(libraryCachePath())
+		var health healthMsg
+		for _, root := range videoDirs {
+			h := rootHealth{root: root}
+			start := time.Now()
+			h.err = readDir(root, opts, nil).err
+			h.latency = time.Since(start)
+			if h.err == nil {
+				h.network = isNetworkFS(root)
+			}
+			if rc := cache.Roots[root]; rc != nil {
+				h.indexed, h.files = rc.Scanned, rc.Videos.len()
+			}
+			health = append(health, h)
+		}
+		return health
+	}
+}
+
+func (m model) updateHealth(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch keys().command("health", msg.String()) {
+	case "check":
+		m.health = nil
+		return m, checkRoots(m.viewContext())
+	case "close":
+		m.healthMode = false
+		m.leaveView()
+	}
+	return m, nil
+}
+
+func (m model) viewHealth() string {
+	s := keys().help("Library roots - %s to check again, %s to close", "health", "check", "close") + "\n\n"
+	if m.health == nil {
+		return s + "checking...\n"
+	}
+	counts := m.rootCounts()
+	for _, h := range m.health {
+		s += truncateMiddle(h.root, m.width) + "\n"
+		if h.err != nil {
+			s += "  " + failStyle.Render("unavailable: "+h.err.Error()) + "\n"
+		} else {
+			kind := "local"
+			if h.network {
+				kind = "network"
+			}
+			latency := "under 1ms"
+			if h.latency >= time.Millisecond {
+				latency = h.latency.Round(time.Millisecond).String()
+			}
+			s += "  " + okStyle.Render("available") + fmt.Sprintf(" (%s, answered in %s)", kind, latency) + "\n"
+		}
+		if h.indexed.IsZero() {
+			s += "  never fully indexed"
+		} else {
+			s += fmt.Sprintf("  indexed %s, %d files", formatDateTime(h.indexed), h.files)
+		}
+		s += fmt.Sprintf(", %d listed now\n\n", counts[h.root])
+	}
+	return s
+}
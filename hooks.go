@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// hookEnv describes video to hook commands through environment variables.
+// The title and year are those shown in the list (see titleYear), and the
+// genres those scraped.
+func hookEnv(video string, ix *index, st *state) []string {
+	title, year := titleYear(video, ix)
+	var genres []string
+	if md := ix.metadata(video); md != nil {
+		genres = md.Genres
+	}
+	var yearText string
+	if year > 0 {
+		yearText = strconv.Itoa(year)
+	}
+	return []string{
+		"VIDEO_FILE=" + video,
+		"VIDEO_TITLE=" + title,
+		"VIDEO_YEAR=" + yearText,
+		"VIDEO_GENRES=" + strings.Join(genres, ","),
+		"VIDEO_TAGS=" + strings.Join(ix.tags(video), ","),
+		"VIDEO_RATING=" + strconv.Itoa(st.Ratings[video]),
+		"VIDEO_PROFILE=" + profileName,
+	}
+}
+
+// runHook runs a configured hook command through the shell with env added to
+// the launcher's environment. An empty command does nothing.
+func runHook(name, command string, env []string, out io.Writer) error {
+	if command == "" {
+		return nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	logger.Info("running hook", "hook", name, "cmd", command)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook: %w", name, err)
+	}
+	return nil
+}
+
+// finishedEnv adds how playback ended to env for the post_play hook.
+func finishedEnv(env []string, end playbackEnd) []string {
+	done := "0"
+	if end == endFinished {
+		done = "1"
+	}
+	return append(env, "VIDEO_FINISHED="+done, "VIDEO_END="+end.String())
+}
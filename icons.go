@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// iconSets are the icons shown before entries with icons = "nerd", which
+// needs a Nerd Font in the terminal, or icons = "ascii", which works in any.
+var iconSets = map[string]map[string]string{
+	"nerd": {
+		"movie":  "\uf008", // nf-fa-film
+		"tv":     "\uf26c", // nf-fa-tv
+		"disc":   "\uf192", // nf-fa-dot_circle_o
+		"image":  "\uf03e", // nf-fa-picture_o
+		"folder": "\uf07b", // nf-fa-folder
+		"4k":     "4K",
+	},
+	"ascii": {
+		"movie":  "[M]",
+		"tv":     "[TV]",
+		"disc":   "[D]",
+		"image":  "[I]",
+		"folder": "[/]",
+		"4k":     "4K",
+	},
+}
+
+var (
+	// discRip matches the release tags of videos ripped from a DVD or
+	// Blu-ray.
+	discRip = regexp.MustCompile(`(?i)(^|[^a-z0-9])(bluray|blu-ray|bdrip|brrip|bdremux|remux|dvdrip|dvd)([^a-z0-9]|$)`)
+	// uhd matches the release tags of 4K videos.
+	uhd = regexp.MustCompile(`(?i)(^|[^a-z0-9])(2160p|4k|uhd)([^a-z0-9]|$)`)
+)
+
+// mediaKind tells what video is for its icon: "image", "tv" for episodes,
+// "disc" for DVD and Blu-ray rips (including VIDEO_TS and BDMV folders) or
+// "movie".
+func mediaKind(video string) string {
+	if isImageFile(video) {
+		return "image"
+	}
+	if _, ok := parseEpisode(video); ok {
+		return "tv"
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(video)), "/") {
+		if strings.EqualFold(dir, "VIDEO_TS") || strings.EqualFold(dir, "BDMV") {
+			return "disc"
+		}
+	}
+	if discRip.MatchString(filepath.Base(video)) {
+		return "disc"
+	}
+	return "movie"
+}
+
+// icon returns the icon of kind followed by a space, or "" when icons are
+// off.
+func icon(kind string) string {
+	set := iconSets[cfg.Icons]
+	if set == nil {
+		return ""
+	}
+	return set[kind] + " "
+}
+
+// videoIcon returns the icons shown before video: its kind and, for 4K
+// videos, a 4K badge.
+func videoIcon(video string) string {
+	if iconSets[cfg.Icons] == nil {
+		return ""
+	}
+	s := icon(mediaKind(video))
+	if uhd.MatchString(filepath.Base(video)) {
+		s += icon("4k")
+	}
+	return s
+}
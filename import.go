@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var syncClient = &http.Client{Timeout: 30 * time.Second}
+
+// watchRecord is what a media server or tracker knows about one film or
+// episode. Episodes have show set.
+type watchRecord struct {
+	title   string
+	year    int
+	show    string
+	season  int
+	episode int
+	// watched is when it was last watched to the end, zero if never.
+	watched time.Time
+	// position is how far into it playback stopped, in seconds. Services
+	// that only know the percentage set progress instead.
+	position float64
+	progress float64
+	// stars is a 1-5 rating, from files written by "export".
+	stars int
+}
+
+// fetchJSON sends req and decodes the JSON answer into out.
+func fetchJSON(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := syncClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// matchKey reduces a title to lowercase letters and digits so that "The
+// Office (US)" and "the.office.us" compare equal.
+func matchKey(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// localMatcher finds local files by title and year, or by show and episode.
+type localMatcher struct {
+	movies   map[string]string
+	titles   map[string][]string
+	episodes map[string]string
+}
+
+func newLocalMatcher(videos []string, ix *index) localMatcher {
+	lm := localMatcher{movies: map[string]string{}, titles: map[string][]string{}, episodes: map[string]string{}}
+	for _, video := range videos {
+		if ep, ok := parseEpisode(video); ok {
+			ep = withScraped(ep, ix)
+			lm.episodes[episodeKey(ep.show, ep.season, ep.episode)] = video
+			continue
+		}
+		title, year := titleYear(video, ix)
+		lm.movies[fmt.Sprintf("%s|%d", matchKey(title), year)] = video
+		lm.titles[matchKey(title)] = append(lm.titles[matchKey(title)], video)
+	}
+	return lm
+}
+
+func episodeKey(show string, season, episode int) string {
+	return fmt.Sprintf("%s|%d|%d", matchKey(show), season, episode)
+}
+
+// find returns the local file of r. A film without a year, locally or in
+// r, matches by title when only one file has that title.
+func (lm localMatcher) find(r watchRecord) (string, bool) {
+	if r.show != "" {
+		video, ok := lm.episodes[episodeKey(r.show, r.season, r.episode)]
+		return video, ok
+	}
+	if video, ok := lm.movies[fmt.Sprintf("%s|%d", matchKey(r.title), r.year)]; ok {
+		return video, true
+	}
+	if video, ok := lm.movies[matchKey(r.title)+"|0"]; ok {
+		return video, true
+	}
+	if videos := lm.titles[matchKey(r.title)]; r.year == 0 && len(videos) == 1 {
+		return videos[0], true
+	}
+	return "", false
+}
+
+// importSummary counts what importRecords changed.
+type importSummary struct {
+	matched, watched, resumed, rated int
+}
+
+// importRecords marks the local files of records as watched and saves their
+// resume positions and ratings. Local data wins: files already watched,
+// rated or with a resume position are left alone.
+func importRecords(records []watchRecord, videos []string, ix *index, st *state) (importSummary, error) {
+	var sum importSummary
+	lm := newLocalMatcher(videos, ix)
+	for _, r := range records {
+		video, ok := lm.find(r)
+		if !ok {
+			logger.Debug("no local file", "title", r.title, "year", r.year, "show", r.show)
+			continue
+		}
+		sum.matched++
+		if r.stars > 0 && st.Ratings[video] == 0 {
+			st.setRating(video, r.stars)
+			sum.rated++
+		}
+		if !st.Watched[video].IsZero() {
+			continue
+		}
+		if !r.watched.IsZero() {
+			st.Watched[video] = r.watched
+			sum.watched++
+			continue
+		}
+		position := r.position
+		if md := ix.metadata(video); position == 0 && md != nil {
+			position = r.progress / 100 * float64(md.Runtime*60)
+		}
+		if position > 0 && !hasResume(video) {
+			if err := writeResume(video, position); err != nil {
+				return sum, err
+			}
+			sum.resumed++
+		}
+	}
+	return sum, st.save()
+}
+
+// writeResume stores a resume position for video the way mpv does, so the
+// next playback starts there.
+func writeResume(video string, seconds float64) error {
+	data := fmt.Sprintf("# %s\nstart=%f\n", video, seconds)
+	return replaceFile(filepath.Join(watchLaterDir(), watchLaterName(video)), []byte(data))
+}
+
+// importWatchState is the "import" subcommand: it pulls watched flags and
+// resume positions from a service, or a file written by "export", and
+// applies them to the local library.
+func importWatchState(ctx context.Context, args []string, ix *index, st *state) error {
+	if len(args) != 1 {
+		return errors.New("usage: movie-launcher import jellyfin|plex|trakt|<file.json>")
+	}
+	var records []watchRecord
+	var err error
+	name := args[0]
+	switch name {
+	case "jellyfin":
+		name = "Jellyfin"
+		records, err = jellyfinRecords(ctx, cfg.Jellyfin)
+	case "plex":
+		name = "Plex"
+		records, err = plexRecords(ctx, cfg.Plex)
+	case "trakt":
+		name = "Trakt"
+		records, err = traktRecords(ctx, cfg.Trakt)
+	default:
+		records, err = fileRecords(name)
+	}
+	if err != nil {
+		return err
+	}
+	videos, err := searchVideos(ctx, nil, ix, st)
+	if err != nil {
+		logger.Warn("scan incomplete", "err", err)
+	}
+	sum, err := importRecords(records, videos, ix, st)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Matched %d of %d items from %s: marked %d watched, saved %d resume positions and %d ratings\n",
+		sum.matched, len(records), name, sum.watched, sum.resumed, sum.rated)
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// indexEntry holds everything the launcher remembers about a single file.
+type indexEntry struct {
+	Tags     []string  `json:"tags,omitempty"`
+	Metadata *metadata `json:"metadata,omitempty"`
+	// Fingerprint recognises the file after a move (see fingerprint).
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Options are mpv options changed while the file played, such as the
+	// audio and subtitle track, applied again the next time.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// metadata describes a video as reported by scraper plugins.
+type metadata struct {
+	Title   string   `json:"title,omitempty"`
+	Year    int      `json:"year,omitempty"`
+	Runtime int      `json:"runtime,omitempty"` // minutes
+	Rating  float64  `json:"rating,omitempty"`  // community rating out of 10
+	Plot    string   `json:"plot,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+	Actors  []string `json:"actors,omitempty"`
+	// Certification is the age rating, such as "PG-13" or "FSK 12".
+	Certification string `json:"certification,omitempty"`
+	// Season and Episode number an episode as its show's guide does, where
+	// specials are season 0, and Aired is when it first aired, as
+	// "2009-04-05". They order the episodes of a show (see showEpisodes).
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
+	Aired   string `json:"aired,omitempty"`
+}
+
+// merge fills fields of md that are still empty from other.
+func (md *metadata) merge(other metadata) {
+	if md.Title == "" {
+		md.Title = other.Title
+	}
+	if md.Year == 0 {
+		md.Year = other.Year
+	}
+	if md.Runtime == 0 {
+		md.Runtime = other.Runtime
+	}
+	if md.Rating == 0 {
+		md.Rating = other.Rating
+	}
+	if md.Plot == "" {
+		md.Plot = other.Plot
+	}
+	if len(md.Genres) == 0 {
+		md.Genres = other.Genres
+	}
+	if len(md.Actors) == 0 {
+		md.Actors = other.Actors
+	}
+	if md.Certification == "" {
+		md.Certification = other.Certification
+	}
+	if md.Episode == 0 {
+		md.Season, md.Episode = other.Season, other.Episode
+	}
+	if md.Aired == "" {
+		md.Aired = other.Aired
+	}
+}
+
+func (e *indexEntry) empty() bool {
+	return len(e.Tags) == 0 && e.Metadata == nil && e.Fingerprint == "" && len(e.Options) == 0
+}
+
+// index is the on-disk record of per-file data, keyed by absolute path.
+type index struct {
+	path string
+	// loaded is the file as it was loaded or last saved (see state.loaded).
+	loaded  []byte
+	Entries map[string]*indexEntry `json:"entries"`
+}
+
+func loadIndex(path string) (*index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	ix, err := parseIndex(data)
+	if err != nil {
+		return nil, err
+	}
+	ix.path = path
+	ix.loaded = data
+	return ix, nil
+}
+
+// parseIndex reads an index file's contents, or none when data is nil.
+func parseIndex(data []byte) (*index, error) {
+	ix := &index{}
+	if data != nil {
+		if err := json.Unmarshal(data, ix); err != nil {
+			return nil, err
+		}
+	}
+	if ix.Entries == nil {
+		ix.Entries = map[string]*indexEntry{}
+	}
+	return ix, nil
+}
+
+// save writes ix to its file, merging the entries changed since it was
+// loaded into what another instance saved meanwhile, as state.save does.
+func (ix *index) save() error {
+	return saveMerged(ix.path, func(saved []byte) ([]byte, error) {
+		if !bytes.Equal(saved, ix.loaded) {
+			theirs, err := parseIndex(saved)
+			if err != nil {
+				return nil, err
+			}
+			base, err := parseIndex(ix.loaded)
+			if err != nil {
+				return nil, err
+			}
+			ix.Entries = mergeChanges(theirs.Entries, base.Entries, ix.Entries)
+		}
+		data, err := json.MarshalIndent(ix, "", "  ")
+		if err == nil {
+			ix.loaded = data
+		}
+		return data, err
+	})
+}
+
+// entry returns the stored entry for path, or nil if nothing is known about it.
+func (ix *index) entry(path string) *indexEntry {
+	return ix.Entries[path]
+}
+
+func (ix *index) tags(path string) []string {
+	if e := ix.entry(path); e != nil {
+		return e.Tags
+	}
+	return nil
+}
+
+// allTags returns every tag in use, sorted.
+func (ix *index) allTags() []string {
+	seen := map[string]bool{}
+	for _, e := range ix.Entries {
+		for _, tag := range e.Tags {
+			seen[tag] = true
+		}
+	}
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// ensure returns the entry for path, creating it if needed.
+func (ix *index) ensure(path string) *indexEntry {
+	e := ix.Entries[path]
+	if e == nil {
+		e = &indexEntry{}
+		ix.Entries[path] = e
+	}
+	return e
+}
+
+func (ix *index) metadata(path string) *metadata {
+	if e := ix.entry(path); e != nil {
+		return e.Metadata
+	}
+	return nil
+}
+
+func (ix *index) addTag(path, tag string) {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return
+	}
+	e := ix.ensure(path)
+	for _, t := range e.Tags {
+		if t == tag {
+			return
+		}
+	}
+	e.Tags = append(e.Tags, tag)
+	sort.Strings(e.Tags)
+}
+
+func (ix *index) removeTag(path, tag string) {
+	tag = normalizeTag(tag)
+	e := ix.Entries[path]
+	if e == nil {
+		return
+	}
+	for i, t := range e.Tags {
+		if t == tag {
+			e.Tags = append(e.Tags[:i], e.Tags[i+1:]...)
+			break
+		}
+	}
+	if e.empty() {
+		delete(ix.Entries, path)
+	}
+}
+
+// certification returns the age rating of path, or "" if none was scraped.
+func (ix *index) certification(path string) string {
+	if md := ix.metadata(path); md != nil {
+		return md.Certification
+	}
+	return ""
+}
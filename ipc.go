@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// mpvEvent is an asynchronous message from mpv, such as a property change.
+type mpvEvent struct {
+	Event string          `json:"event"`
+	ID    int             `json:"id"`
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data"`
+	// Reason says why an end-file event happened: "eof", "stop", "quit",
+	// "error" or "redirect".
+	Reason string `json:"reason"`
+}
+
+type mpvResponse struct {
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// mpvClient talks to mpv over its JSON IPC socket (--input-ipc-server).
+type mpvClient struct {
+	conn    net.Conn
+	events  chan mpvEvent
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan mpvResponse
+	// percent and endReason follow percent-pos (-1 until reported) and the
+	// reason of the last end-file event, for endOf; done is closed once mpv
+	// has gone away.
+	percent   float64
+	endReason string
+	done      chan struct{}
+	// entry and entries follow playlist-pos, leaving out the -1 of no
+	// entry, and playlist-count, so endOf knows whether the last entry of
+	// a playlist was playing.
+	entry   int
+	entries int
+	// options and tweaks follow the rememberedOptions (see trackOption).
+	options map[string]string
+	tweaks  map[string]string
+	// speed is the last playback speed reported, 0 until then.
+	speed float64
+}
+
+// dialMpv connects to socket, retrying until mpv has created it or timeout
+// expires.
+func dialMpv(socket string, timeout time.Duration) (*mpvClient, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			c := &mpvClient{
+				conn:    conn,
+				events:  make(chan mpvEvent, 64),
+				pending: map[int]chan mpvResponse{},
+				percent: -1,
+				done:    make(chan struct{}),
+				options: map[string]string{},
+				tweaks:  map[string]string{},
+			}
+			go c.read()
+			return c, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (c *mpvClient) read() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		logger.Debug("ipc recv", "data", string(line))
+		var probe struct {
+			Event     string `json:"event"`
+			RequestID *int   `json:"request_id"`
+		}
+		if json.Unmarshal(line, &probe) != nil {
+			continue
+		}
+		if probe.Event != "" {
+			var ev mpvEvent
+			if json.Unmarshal(line, &ev) == nil {
+				c.track(ev)
+				c.events <- ev
+			}
+			continue
+		}
+		if probe.RequestID != nil {
+			var resp mpvResponse
+			json.Unmarshal(line, &resp)
+			c.mu.Lock()
+			ch := c.pending[resp.RequestID]
+			delete(c.pending, resp.RequestID)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- resp
+			}
+		}
+	}
+	close(c.events)
+	close(c.done)
+}
+
+// track remembers where playback got to and why it ended.
+func (c *mpvClient) track(ev mpvEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case ev.Event == "property-change" && ev.Name == "percent-pos":
+		json.Unmarshal(ev.Data, &c.percent)
+	case ev.Event == "property-change" && ev.Name == "playlist-pos":
+		var pos int
+		if json.Unmarshal(ev.Data, &pos) == nil && pos >= 0 {
+			c.entry = pos
+		}
+	case ev.Event == "property-change" && ev.Name == "playlist-count":
+		json.Unmarshal(ev.Data, &c.entries)
+	case ev.Event == "property-change" && ev.Name == "speed":
+		json.Unmarshal(ev.Data, &c.speed)
+	case ev.Event == "property-change":
+		c.trackOption(ev)
+	case ev.Event == "end-file":
+		c.endReason = ev.Reason
+	}
+}
+
+// lastSpeed returns the last playback speed mpv reported, or 0.
+func (c *mpvClient) lastSpeed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+// ended waits briefly for mpv to close the connection, then returns the
+// last percent-pos (-1 if never reported) and end-file reason.
+func (c *mpvClient) ended() (float64, string) {
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.percent, c.endReason
+}
+
+// lastEntry reports whether the last entry of the playlist was playing, as
+// it always is without a playlist.
+func (c *mpvClient) lastEntry() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entry >= c.entries-1
+}
+
+// command sends an mpv input command and waits for its result.
+func (c *mpvClient) command(args ...any) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan mpvResponse, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	data, err := json.Marshal(map[string]any{"command": args, "request_id": id})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("ipc send", "data", string(data))
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != "success" {
+			return nil, errors.New(resp.Error)
+		}
+		return resp.Data, nil
+	case <-time.After(2 * time.Second):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("mpv did not answer %v", args[0])
+	}
+}
+
+// observe asks mpv to report changes to the named property as events.
+func (c *mpvClient) observe(id int, name string) error {
+	_, err := c.command("observe_property", id, name)
+	return err
+}
+
+func (c *mpvClient) close() error {
+	return c.conn.Close()
+}
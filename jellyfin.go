@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// jellyfinConfig is the [jellyfin] table. User picks the account when the
+// server has several.
+type jellyfinConfig struct {
+	URL    string `toml:"url"`
+	APIKey string `toml:"api_key"`
+	User   string `toml:"user"`
+}
+
+func (c jellyfinConfig) get(ctx context.Context, path string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(c.URL, "/")+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	return fetchJSON(req, out)
+}
+
+// jellyfinRecords lists the played and partly played films and episodes of
+// the configured Jellyfin (or Emby) user.
+func jellyfinRecords(ctx context.Context, c jellyfinConfig) ([]watchRecord, error) {
+	if c.URL == "" || c.APIKey == "" {
+		return nil, fmt.Errorf("set url and api_key under [jellyfin] in config.toml")
+	}
+	var users []struct {
+		ID   string `json:"Id"`
+		Name string `json:"Name"`
+	}
+	if err := c.get(ctx, "/Users", url.Values{}, &users); err != nil {
+		return nil, err
+	}
+	var userID string
+	for _, u := range users {
+		if strings.EqualFold(u.Name, c.User) || c.User == "" && len(users) == 1 {
+			userID = u.ID
+		}
+	}
+	if userID == "" {
+		return nil, fmt.Errorf("set user under [jellyfin] to one of the %d users on the server", len(users))
+	}
+
+	var resp struct {
+		Items []struct {
+			Name     string `json:"Name"`
+			Year     int    `json:"ProductionYear"`
+			Type     string `json:"Type"`
+			Series   string `json:"SeriesName"`
+			Season   int    `json:"ParentIndexNumber"`
+			Episode  int    `json:"IndexNumber"`
+			UserData struct {
+				Played         bool      `json:"Played"`
+				PositionTicks  int64     `json:"PlaybackPositionTicks"`
+				LastPlayedDate time.Time `json:"LastPlayedDate"`
+			} `json:"UserData"`
+		} `json:"Items"`
+	}
+	params := url.Values{"Recursive": {"true"}, "IncludeItemTypes": {"Movie,Episode"}, "EnableUserData": {"true"}}
+	if err := c.get(ctx, "/Users/"+userID+"/Items", params, &resp); err != nil {
+		return nil, err
+	}
+	var records []watchRecord
+	for _, item := range resp.Items {
+		data := item.UserData
+		if !data.Played && data.PositionTicks == 0 {
+			continue
+		}
+		r := watchRecord{title: item.Name, year: item.Year}
+		if item.Type == "Episode" {
+			r.show, r.season, r.episode = item.Series, item.Season, item.Episode
+		}
+		if data.Played {
+			r.watched = data.LastPlayedDate
+			if r.watched.IsZero() {
+				r.watched = time.Now()
+			}
+		} else {
+			// Jellyfin counts in ticks of 100ns.
+			r.position = float64(data.PositionTicks) / 1e7
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
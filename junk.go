@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultJunk recognises scene samples, trailers and the adverts some
+// release groups add. Each pattern is tried against the name of the folder
+// holding a file and its file name without extension, joined by a slash
+// (see junkName).
+var defaultJunk = []*regexp.Regexp{
+	// Anything inside a "Sample" or "Trailers" folder.
+	regexp.MustCompile(`(?i)^(samples?|trailers?)/`),
+	// "Movie.2010.1080p-sample", "Movie (2010)-trailer".
+	regexp.MustCompile(`(?i)[ ._-](sample|trailer|teaser)$`),
+	// "sample-movie.2010.1080p", or a file called just "trailer".
+	regexp.MustCompile(`(?i)/(sample|trailer)(-.*)?$`),
+	// "RARBG.mp4", "RARBG.com.mp4", "ETRG.mp4".
+	regexp.MustCompile(`(?i)/(rarbg(\.com)?|etrg)$`),
+}
+
+// junkName is the string junk patterns are tried against: the folder of
+// video and its file name without extension, such as "Sample/movie-sample".
+func junkName(video string) string {
+	name := strings.TrimSuffix(filepath.Base(video), filepath.Ext(video))
+	return filepath.Base(filepath.Dir(video)) + "/" + name
+}
+
+// isJunk reports whether video looks like a sample, trailer or advert
+// rather than something to watch.
+func isJunk(video string) bool {
+	if cfg.Junk == "off" {
+		return false
+	}
+	patterns := cfg.junkPatterns
+	if patterns == nil {
+		patterns = defaultJunk
+	}
+	name := junkName(video)
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hidesJunk reports whether junk is left out of the list like hidden
+// files, rather than sorted after everything else.
+func hidesJunk() bool {
+	return cfg.Junk == "hide"
+}
+
+// demoteJunk moves the junk in videos after everything else, keeping the
+// order within both groups.
+func demoteJunk(videos []string) []string {
+	if cfg.Junk == "off" {
+		return videos
+	}
+	var wanted, junk []string
+	for _, video := range videos {
+		if isJunk(video) {
+			junk = append(junk, video)
+		} else {
+			wanted = append(wanted, video)
+		}
+	}
+	return append(wanted, junk...)
+}
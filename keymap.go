@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// bindings are the default keys of the commands of each mode of the
+// interface. A key runs one command within a mode and may run another in
+// a different mode: "x" hides a video in the list and delays the subtitles
+// while playing. The [keys] settings rebind commands mode by mode.
+var bindings = map[string]map[string][]string{
+	"list": {
+		"quit":           {"q", "ctrl+c"},
+		"filter":         {"/"},
+		"commands":       {":"},
+		"tag":            {"#"},
+		"rate":           {"r"},
+		"jump":           {"'"},
+		"jump-again":     {";"},
+		"syncplay":       {"Y"},
+		"next-episode":   {"n"},
+		"now-playing":    {"p"},
+		"loudnorm":       {"N"},
+		"screen":         {"D"},
+		"actions":        {"a"},
+		"hide":           {"x"},
+		"hide-folder":    {"X"},
+		"pin":            {"P"},
+		"folders":        {"b"},
+		"roots":          {"R"},
+		"trailer":        {"t"},
+		"thumbnails":     {"v"},
+		"health":         {"H"},
+		"rescan":         {"ctrl+r"},
+		"checksums":      {"V"},
+		"show-hidden":    {"."},
+		"sort":           {"s"},
+		"then-sort":      {"S"},
+		"names":          {"d"},
+		"columns":        {"c"},
+		"up":             {"up", "k"},
+		"down":           {"down", "j"},
+		"half-page-up":   {"ctrl+u"},
+		"half-page-down": {"ctrl+d"},
+		"page-up":        {"pgup"},
+		"page-down":      {"pgdown"},
+		"top":            {"home", "g"},
+		"bottom":         {"end", "G"},
+		"play":           {"enter"},
+		"listen":         {"l"},
+		// The bulk commands are run from the command palette.
+		"mark-all-watched": {},
+		"tag-all":          {},
+		"export-playlist":  {},
+	},
+	"dashboard": {
+		"quit":        {"q", "ctrl+c"},
+		"list":        {"tab", "esc"},
+		"now-playing": {"p"},
+		"up":          {"up", "k"},
+		"down":        {"down", "j"},
+		"play":        {"enter"},
+	},
+	"search": {
+		"accept":   {"enter"},
+		"cancel":   {"esc", "ctrl+c"},
+		"complete": {"tab"},
+	},
+	// Typing tags, a playlist file or the PIN; other keys are typed.
+	"tag": {
+		"accept": {"enter"},
+		"cancel": {"esc", "ctrl+c"},
+	},
+	"playlist": {
+		"accept": {"enter"},
+		"cancel": {"esc", "ctrl+c"},
+	},
+	"pin": {
+		"accept": {"enter"},
+		"cancel": {"esc"},
+	},
+	// Typing a command; other keys are typed.
+	"palette": {
+		"up":     {"up", "ctrl+p"},
+		"down":   {"down", "ctrl+n"},
+		"run":    {"enter"},
+		"cancel": {"esc", "ctrl+c"},
+	},
+	// Answering a question asked before a destructive action; any other
+	// key answers no.
+	"confirm": {
+		"yes":    {"y", "Y", "enter"},
+		"no":     {"n", "N", "esc"},
+		"always": {"a", "A"},
+	},
+	"now-playing": {
+		"close":             {"esc", "p", "tab"},
+		"stop":              {"q"},
+		"pause":             {"space"},
+		"seek-back":         {"left"},
+		"seek-forward":      {"right"},
+		"seek-back-more":    {"down"},
+		"seek-forward-more": {"up"},
+		"previous-chapter":  {"pgup"},
+		"next-chapter":      {"pgdown"},
+		"chapters":          {"c"},
+		"audio-track":       {"#"},
+		"subtitles":         {"j"},
+		"screenshot":        {"s"},
+		"loudnorm":          {"N"},
+		"faster":            {"]"},
+		"slower":            {"["},
+		"normal-speed":      {"backspace"},
+		"ab-loop":           {"l"},
+		"loop":              {"L"},
+		"subtitles-earlier": {"z"},
+		"subtitles-later":   {"x"},
+		"audio-earlier":     {"-"},
+		"audio-later":       {"+", "="},
+	},
+	"chapters": {
+		"up":    {"up", "k"},
+		"down":  {"down", "j"},
+		"jump":  {"enter"},
+		"close": {"esc", "c"},
+	},
+	"folders": {
+		"up":        {"up", "k"},
+		"down":      {"down", "j"},
+		"page-up":   {"pgup"},
+		"page-down": {"pgdown"},
+		"top":       {"home", "g"},
+		"bottom":    {"end", "G"},
+		"play":      {"enter"},
+		"open":      {"right", "l"},
+		"parent":    {"backspace", "left", "h"},
+		"path":      {"B"},
+		"filter":    {"/"},
+		"sort":      {"s"},
+		"then-sort": {"S"},
+		"pin":       {"P"},
+		"close":     {"b", "esc", "q"},
+	},
+	// Picking a folder from the path above the folder view.
+	"path": {
+		"left":  {"left", "h"},
+		"right": {"right", "l"},
+		"first": {"home", "g"},
+		"go":    {"enter"},
+		"close": {"esc", "B", "q"},
+	},
+	"editions": {
+		"up":    {"up", "k"},
+		"down":  {"down", "j"},
+		"play":  {"enter"},
+		"close": {"esc", "q"},
+	},
+	"actions": {
+		"up":    {"up", "k"},
+		"down":  {"down", "j"},
+		"run":   {"enter"},
+		"close": {"esc", "a", "q"},
+	},
+	"roots": {
+		"up":     {"up", "k"},
+		"down":   {"down", "j"},
+		"choose": {"enter"},
+		"close":  {"esc", "R", "q"},
+	},
+	"health": {
+		"check": {"r"},
+		"close": {"esc", "H", "q"},
+	},
+	"thumbnails": {
+		"play":  {"enter"},
+		"close": {"esc", "v", "q"},
+	},
+	"checksums": {
+		"verify": {"r"},
+		"close":  {"esc", "V", "q"},
+	},
+}
+
+// keymap holds the commands the keys of each mode run, by mode and key,
+// and the keys bound to each command, by mode and command.
+type keymap struct {
+	commands map[string]map[string]string
+	keys     map[string]map[string][]string
+}
+
+// defaultKeymap is the keymap of the default bindings.
+var defaultKeymap, _ = newKeymap(nil)
+
+// keyList is the keys of a command in the [keys] settings: one key, such
+// as "P", or a list of them.
+type keyList []string
+
+func (k *keyList) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*k = keyList{v}
+	case []any:
+		*k = nil
+		for _, key := range v {
+			s, ok := key.(string)
+			if !ok {
+				return fmt.Errorf("want keys as strings, not %v", key)
+			}
+			*k = append(*k, s)
+		}
+	default:
+		return fmt.Errorf("want a key or a list of keys, not %v", v)
+	}
+	return nil
+}
+
+// newKeymap builds the keymap of the default bindings with the commands in
+// overrides, by mode, bound to their keys instead. It fails on unknown
+// modes and commands and when a key would run two commands of a mode.
+func newKeymap(overrides map[string]map[string]keyList) (*keymap, error) {
+	for mode, commands := range overrides {
+		if bindings[mode] == nil {
+			return nil, fmt.Errorf("keys: unknown mode %q", mode)
+		}
+		for command := range commands {
+			if bindings[mode][command] == nil {
+				return nil, fmt.Errorf("keys.%s: unknown command %q", mode, command)
+			}
+		}
+	}
+	km := &keymap{commands: map[string]map[string]string{}, keys: map[string]map[string][]string{}}
+	for mode, commands := range bindings {
+		km.commands[mode] = map[string]string{}
+		km.keys[mode] = map[string][]string{}
+		names := make([]string, 0, len(commands))
+		for command := range commands {
+			names = append(names, command)
+		}
+		// Sorted so the same conflict is always reported.
+		slices.Sort(names)
+		for _, command := range names {
+			keys := commands[command]
+			if override, ok := overrides[mode][command]; ok {
+				keys = override
+			}
+			for _, key := range keys {
+				key = keyName(key)
+				if other, ok := km.commands[mode][key]; ok {
+					return nil, fmt.Errorf("keys.%s: %q runs both %s and %s", mode, key, other, command)
+				}
+				km.commands[mode][key] = command
+				km.keys[mode][command] = append(km.keys[mode][command], key)
+			}
+		}
+	}
+	return km, nil
+}
+
+// keyName is key as tea.KeyMsg.String names it: "space" is " ".
+func keyName(key string) string {
+	if strings.EqualFold(key, "space") {
+		return " "
+	}
+	return key
+}
+
+// keys returns the keymap in use.
+func keys() *keymap {
+	if cfg.keymap == nil {
+		return defaultKeymap
+	}
+	return cfg.keymap
+}
+
+// command returns the command key runs in mode, or "" when it runs none.
+func (km *keymap) command(mode, key string) string {
+	return km.commands[mode][key]
+}
+
+// key returns the first key bound to command in mode, for showing, or ""
+// when it has none.
+func (km *keymap) key(mode, command string) string {
+	keys := km.keys[mode][command]
+	if len(keys) == 0 {
+		return ""
+	}
+	if keys[0] == " " {
+		return "space"
+	}
+	return keys[0]
+}
+
+// keyLabels are how the help lines show keys that tea.KeyMsg.String names
+// by a word, before translation.
+var keyLabels = map[string]string{
+	"up":        "↑",
+	"down":      "↓",
+	"left":      "←",
+	"right":     "→",
+	"enter":     "Enter",
+	"esc":       "Esc",
+	"tab":       "Tab",
+	"backspace": "Backspace",
+	" ":         "Space",
+	"pgup":      "PgUp",
+	"pgdown":    "PgDn",
+	"home":      "Home",
+	"end":       "End",
+}
+
+// label returns the first key bound to command in mode as the help lines
+// show it, such as "Enter" or "↑", or "" when it has none.
+func (km *keymap) label(mode, command string) string {
+	keys := km.keys[mode][command]
+	if len(keys) == 0 {
+		return ""
+	}
+	if label, ok := keyLabels[keys[0]]; ok {
+		return tr(label)
+	}
+	return keys[0]
+}
+
+// help translates format, a help line, and fills in the keys of commands
+// in mode, one for each %s, so the line shows the keys in use.
+func (km *keymap) help(format, mode string, commands ...string) string {
+	labels := make([]any, len(commands))
+	for i, command := range commands {
+		labels[i] = km.label(mode, command)
+	}
+	return trf(format, labels...)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultRefreshAfter is how old the cached file list may get with the
+// "stale" refresh policy before the library is walked again.
+const defaultRefreshAfter = 24 * time.Hour
+
+// libraryCache is the file list of each root from its last complete walk,
+// kept so launches can skip walking the library (see the refresh setting).
+type libraryCache struct {
+	path  string
+	Roots map[string]*rootCache `json:"roots"`
+}
+
+type rootCache struct {
+	Scanned time.Time `json:"scanned"`
+	Videos  videoList `json:"videos"`
+	// Dirs lets the next walk skip reading unchanged directories.
+	Dirs map[string]*dirState `json:"dirs,omitempty"`
+}
+
+// videoList is the cached file list of a root, grouped by folder so that a
+// huge library keeps each folder's path once instead of in front of every
+// file name, in memory and in library.json, where a group is saved as
+// ["/mnt/movies/Alien (1979)", "Alien.mkv", "Aliens.mkv"].
+type videoList []videoGroup
+
+type videoGroup struct {
+	dir   string
+	names []string
+}
+
+// newVideoList groups videos, keeping their order. Videos of a folder are
+// found one after another by the walk, so each folder gets one group.
+func newVideoList(videos []string) videoList {
+	var l videoList
+	for _, video := range videos {
+		dir, name := filepath.Dir(video), filepath.Base(video)
+		if n := len(l); n > 0 && l[n-1].dir == dir {
+			l[n-1].names = append(l[n-1].names, name)
+			continue
+		}
+		l = append(l, videoGroup{dir: dir, names: []string{name}})
+	}
+	return l
+}
+
+// len counts the videos in the list.
+func (l videoList) len() int {
+	n := 0
+	for _, g := range l {
+		n += len(g.names)
+	}
+	return n
+}
+
+// paths returns the full path of every video. The paths of a folder share
+// one allocation, which keeps the garbage collector's work down when a
+// huge library is listed from the cache.
+func (l videoList) paths() []string {
+	paths := make([]string, 0, l.len())
+	for _, g := range l {
+		var b strings.Builder
+		size := 0
+		for _, name := range g.names {
+			size += len(g.dir) + 1 + len(name)
+		}
+		b.Grow(size)
+		for _, name := range g.names {
+			b.WriteString(g.dir)
+			b.WriteByte(filepath.Separator)
+			b.WriteString(name)
+		}
+		all, start := b.String(), 0
+		for _, name := range g.names {
+			end := start + len(g.dir) + 1 + len(name)
+			paths = append(paths, all[start:end])
+			start = end
+		}
+	}
+	return paths
+}
+
+// filter returns the videos that keep reports true for.
+func (l videoList) filter(keep func(video string) bool) videoList {
+	return newVideoList(slices.DeleteFunc(l.paths(), func(video string) bool { return !keep(video) }))
+}
+
+func (g videoGroup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(append([]string{g.dir}, g.names...))
+}
+
+// UnmarshalJSON reads the grouped list, and also the plain list of paths
+// that earlier versions saved.
+func (l *videoList) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	var plain []string
+	for _, item := range items {
+		var group []string
+		if err := json.Unmarshal(item, &group); err == nil {
+			if len(group) > 1 {
+				*l = append(*l, videoGroup{dir: group[0], names: group[1:]})
+			}
+			continue
+		}
+		var path string
+		if err := json.Unmarshal(item, &path); err != nil {
+			return err
+		}
+		plain = append(plain, path)
+	}
+	*l = append(*l, newVideoList(plain)...)
+	return nil
+}
+
+func libraryCachePath() string {
+	return filepath.Join(cacheDir(), "library.json")
+}
+
+// loadLibraryCache reads the cached file lists. A missing or unreadable
+// cache is treated as empty since it can always be rebuilt.
+func loadLibraryCache(path string) *libraryCache {
+	lc := &libraryCache{path: path}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, lc)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("ignoring library cache", "path", path, "err", err)
+	}
+	if lc.Roots == nil {
+		lc.Roots = map[string]*rootCache{}
+	}
+	return lc
+}
+
+func (lc *libraryCache) save() error {
+	data, err := json.Marshal(lc)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(lc.path, data)
+}
+
+// videos returns the cached videos of every library root, or false if any
+// root has not been walked yet.
+func (lc *libraryCache) videos() ([]string, bool) {
+	var videos []string
+	for _, root := range videoDirs {
+		rc := lc.Roots[root]
+		if rc == nil {
+			return nil, false
+		}
+		videos = append(videos, rc.Videos.paths()...)
+	}
+	return videos, true
+}
+
+// fresh reports whether every root was walked within the refresh_after age.
+func (lc *libraryCache) fresh() bool {
+	maxAge := cfg.RefreshAfter.Duration
+	if maxAge <= 0 {
+		maxAge = defaultRefreshAfter
+	}
+	for _, root := range videoDirs {
+		if rc := lc.Roots[root]; rc == nil || time.Since(rc.Scanned) > maxAge {
+			return false
+		}
+	}
+	return true
+}
+
+// useCache decides from the refresh policy whether this launch lists the
+// cached files instead of walking the library. --reindex always walks.
+func useCache(lc *libraryCache, reindex bool) bool {
+	if _, ok := lc.videos(); !ok || reindex {
+		return false
+	}
+	switch cfg.Refresh {
+	case "manual", "background":
+		return true
+	case "stale":
+		return lc.fresh()
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// localeFiles are the bundled translations, one TOML file per language
+// mapping the English text of the interface to its translation.
+//
+//go:embed locales/*.toml
+var localeFiles embed.FS
+
+// messages is the translation of the language in use; text it lacks is
+// shown in English.
+var messages = map[string]string{}
+
+// tr translates s into the language in use.
+func tr(s string) string {
+	if t := messages[s]; t != "" {
+		return t
+	}
+	return s
+}
+
+// trf translates format and formats args with it, like fmt.Sprintf.
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// localeLanguage returns the language code, such as "de", of setting, the
+// language config setting, or when it is empty of the locale in LC_ALL,
+// LC_MESSAGES or LANG ("de_DE.UTF-8" is "de"). The C and POSIX locales are
+// English.
+func localeLanguage(setting string) string {
+	lang := setting
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang != "" {
+			break
+		}
+		lang = os.Getenv(name)
+	}
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang = strings.ToLower(lang)
+	if lang == "" || lang == "c" || lang == "posix" {
+		return "en"
+	}
+	return lang
+}
+
+// setLanguage switches the interface to the language of setting (see
+// localeLanguage). A file of the same name in the locales folder of the
+// config directory adds to the bundled translation or replaces parts of it,
+// and can add a language that is not bundled.
+func setLanguage(setting string) {
+	lang := localeLanguage(setting)
+	catalog := map[string]string{}
+	if lang != "en" {
+		if data, err := localeFiles.ReadFile("locales/" + lang + ".toml"); err == nil {
+			if err := toml.Unmarshal(data, &catalog); err != nil {
+				logger.Warn("bundled translation is invalid", "language", lang, "err", err)
+			}
+		}
+		path := filepath.Join(configDir(), "locales", lang+".toml")
+		if _, err := toml.DecodeFile(path, &catalog); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("reading translation failed", "path", path, "err", err)
+		}
+		if len(catalog) == 0 {
+			logger.Debug("no translation, using English", "language", lang)
+		}
+	}
+	messages = catalog
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// logger receives structured diagnostics. It discards everything unless
+// --log-file or --verbose is given.
+var logger = slog.New(slog.DiscardHandler)
+
+// setupLogging points logger at path, defaulting to movie-launcher.log in
+// the data directory when only verbose is set. Verbose also enables debug
+// records such as skipped files and IPC traffic.
+func setupLogging(path string, verbose bool) (io.Closer, error) {
+	if path == "" && !verbose {
+		return io.NopCloser(nil), nil
+	}
+	if path == "" {
+		path = filepath.Join(dataDir(), "movie-launcher.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	logger = slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level}))
+	return f, nil
+}
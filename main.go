@@ -1,35 +1,186 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/fs"
+	"hash/fnv"
+	"image"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 var (
-	videoDir    = os.Getenv("VIDEO_DIR")
-	videoPlayer = os.Getenv("VIDEO_PLAYER")
+	videoDir      = os.Getenv("VIDEO_DIR")
+	videoPlayer   = os.Getenv("VIDEO_PLAYER")
+	profileName   = "default"
+	showHidden    = false
+	detachPlayer  = false
+	notifications = false
+	pathOnly      = false
 	videoExts     = []string{".mp4", ".mkv", ".avi", ".mov", ".wmv", ".flv", ".webm", ".m4v", ".mpg", ".mpeg", ".3gp", ".ogv"}
+	imageExts     = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".bmp", ".tif", ".tiff"}
 	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	tagColors     = []lipgloss.Color{"1", "2", "3", "4", "5", "6"}
+	starStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	scoreStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	hiddenStyle   = lipgloss.NewStyle().Faint(true)
+	// sortModes lists the list orderings cycled with "s", and the ones to
+	// break ties by cycled with "S". The column modes match the columns of
+	// the column view; "added" is the newest first by modification time,
+	// "folder" groups videos by folder and "episode" orders shows by
+	// season and episode.
+	sortModes = []string{"path", "rating", "title", "year", "score", "runtime", "size", "watched", "added", "folder", "episode"}
+	// displayModes lists the ways a video is labelled, cycled with "d".
+	displayModes = []string{"relative", "absolute", "title"}
 )
 
 type model struct {
-	allVideos    []string
-	videos       []string
-	cursor       int
-	viewportTop  int
-	viewportSize int
-	selected     string
-	quitting     bool
-	searchMode   bool
-	searchInput  textinput.Model
+	allVideos     []string
+	videos        []string
+	cursor        int
+	viewportTop   int
+	viewportSize  int
+	selected      string
+	syncplay      bool
+	audioOnly     bool
+	quitting      bool
+	searchMode    bool
+	searchInput   textinput.Model
+	tagMode       bool
+	tagInput      textinput.Model
+	index         *index
+	state         *state
+	status        string
+	filter        string
+	root          string
+	rootMode      bool
+	rootCursor    int
+	healthMode    bool
+	health        healthMsg
+	sheetMode     bool
+	sheetVideo    string
+	sheet         image.Image
+	prevFilter    string
+	sortMode      int
+	displayMode   int
+	jumping       bool
+	lastJump      string
+	rating        bool
+	settings      profileSettings
+	pinMode       bool
+	pinInput      textinput.Model
+	showHidden    bool
+	dashboard     bool
+	sections      []dashboardSection
+	dashCursor    int
+	width         int
+	height        int
+	columns       bool
+	playing       *playback
+	queued        string
+	nowPlaying    bool
+	chapterMode   bool
+	chapterCursor int
+	actionMode    bool
+	actionCursor  int
+	query         query
+	scan          <-chan scanBatchMsg
+	scanning      bool
+	// labels caches each video's display string so rendering a frame does
+	// not recompute relative paths.
+	labels map[string]string
+	// sizes caches file sizes for the column view and size sort.
+	sizes map[string]int64
+	// count is a vim-style count prefix typed before a movement key.
+	count string
+	// restore is the video to select once scanned, from the last session.
+	restore string
+	// confirm is the question waiting for a yes/no answer, if any.
+	confirm *confirmation
+	// completer offers completions in the filter input.
+	completer completer
+	// forwarded receives searches from launchers started later.
+	forwarded <-chan controlRequest
+	// configTime is the modification time of the config.toml in use.
+	configTime time.Time
+	// offline holds the library roots that could not be reached, whose
+	// videos are listed from the cache but cannot be played.
+	offline map[string]bool
+	// displays are the screens "D" cycles through, listed on first use.
+	displays []display
+	// queuedAudioOnly plays queued without its picture.
+	queuedAudioOnly bool
+	// broken holds the videos that look like incomplete downloads, with
+	// the reason shown in their warning badge.
+	broken map[string]string
+	// verifyMode shows the report of the last checksum verification, which
+	// "r" runs again over the listed videos while verifying is set.
+	verifyMode   bool
+	verifying    bool
+	verifyReport verifyReport
+	verifyErr    error
+	// progress counts what the running scan has covered.
+	progress *scanProgress
+	// ctx is cancelled when the launcher quits, stopping the work still
+	// running in the background. cancelView stops the work started by the
+	// view that is open, such as generating thumbnails, when it is left.
+	ctx        context.Context
+	cancelView context.CancelFunc
+	// treeMode browses the list by folder, from treeDir down, with "b".
+	// crumbMode picks a folder to jump to from the breadcrumb above it.
+	treeMode    bool
+	treeDir     string
+	treeCursor  int
+	crumbMode   bool
+	crumbCursor int
+	// thenMode is the sort mode that orders videos the sort mode ties,
+	// cycled with "S"; "path" adds nothing to the order by path ties are
+	// always broken by.
+	thenMode int
+	// mtimes caches modification times for the "added" sort.
+	mtimes map[string]int64
+	// otherView is the filter and sort order of the view not shown: the
+	// folder view's while the list is, and the list's while the folder
+	// view is (see swapViewSettings).
+	otherView viewSettings
+	// paletteMode shows the command palette, paletteInput holding what was
+	// typed to search it and paletteCursor the command selected.
+	paletteMode   bool
+	paletteInput  textinput.Model
+	paletteCursor int
+	// bulkTag applies the tags typed in tagInput to every listed video
+	// rather than the selected one.
+	bulkTag bool
+	// playlistMode asks in playlistInput for the file to export the
+	// listed videos to.
+	playlistMode  bool
+	playlistInput textinput.Model
+	// parts are the parts of the films split across files among allVideos,
+	// by their first part, which alone is listed (see multiPart).
+	parts map[string][]string
+	// editions are the editions of the films found in several among
+	// allVideos, by the one listed (see editionsOf), and editionKeys caches
+	// what groups them.
+	editions    map[string][]string
+	editionKeys map[string]editionCache
+	// editionMode picks which of the editions of editionVideo to play.
+	editionMode   bool
+	editionCursor int
+	editionVideo  string
 }
 
 func isVideoFile(filename string) bool {
@@ -42,194 +193,1216 @@ func isVideoFile(filename string) bool {
 	return false
 }
 
-func searchVideos(keywords []string) ([]string, error) {
-	var results []string
-	lowerKeywords := make([]string, len(keywords))
-	for i, k := range keywords {
-		lowerKeywords[i] = strings.ToLower(k)
-	}
-
-	err := filepath.WalkDir(videoDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		// Only process video files
-		if !isVideoFile(path) {
-			return nil
-		}
-
-		lowerPath := strings.ToLower(path)
-		matched := true
-		for _, keyword := range lowerKeywords {
-			if !strings.Contains(lowerPath, keyword) {
-				matched = false
-				break
-			}
+func isImageFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, imageExt := range imageExts {
+		if ext == imageExt {
+			return true
 		}
+	}
+	return false
+}
 
-		if matched {
+func searchVideos(ctx context.Context, keywords []string, ix *index, st *state) ([]string, error) {
+	var results []string
+	q := parseQuery(keywords)
+	q.metadata = !pathOnly
+	progress := newScanProgress()
+	stop := showProgress(progress)
+	_, err := scanLibrary(ctx, func(path string) {
+		if q.matches(path, ix, st) {
 			results = append(results, path)
+		} else {
+			logger.Debug("skipped", "path", path, "reason", "no match")
 		}
-		return nil
-	})
-
+	}, nil, progress)
+	stop()
 	return results, err
 }
 
-func initialModel(videos []string) model {
+func initialModel(videos []string, ix *index, st *state, settings profileSettings) model {
 	ti := textinput.New()
-	ti.Placeholder = "filter..."
+	ti.Placeholder = tr("filter...")
 	ti.CharLimit = 100
 
-	return model{
-		allVideos:    videos,
-		videos:       videos,
-		cursor:       0,
-		viewportTop:  0,
-		viewportSize: 20,
-		searchMode:   false,
-		searchInput:  ti,
+	tagInput := textinput.New()
+	tagInput.Placeholder = tr("tag (prefix with - to remove)...")
+	tagInput.CharLimit = 50
+
+	pinInput := textinput.New()
+	pinInput.Placeholder = tr("PIN to exit...")
+	pinInput.EchoMode = textinput.EchoPassword
+	pinInput.CharLimit = 20
+
+	paletteInput := textinput.New()
+	paletteInput.Placeholder = tr("command...")
+	paletteInput.CharLimit = 50
+
+	playlistInput := textinput.New()
+	playlistInput.Placeholder = tr("playlist file...")
+	playlistInput.CharLimit = 200
+
+	m := model{
+		allVideos:     videos,
+		cursor:        0,
+		viewportTop:   0,
+		viewportSize:  20,
+		searchMode:    false,
+		searchInput:   ti,
+		tagInput:      tagInput,
+		index:         ix,
+		state:         st,
+		settings:      settings,
+		pinInput:      pinInput,
+		paletteInput:  paletteInput,
+		playlistInput: playlistInput,
+		showHidden:    showHidden,
+		labels:        make(map[string]string, len(videos)),
+		sizes:         map[string]int64{},
+		mtimes:        map[string]int64{},
+		editionKeys:   map[string]editionCache{},
+		offline:       map[string]bool{},
+		ctx:           context.Background(),
+	}
+	for _, video := range videos {
+		m.label(video)
+	}
+	m.groupVideos()
+	m.videos = m.visibleVideos()
+	return m
+}
+
+// viewContext stops the work of the view left last, if any, and returns a
+// context for the work of the view now open, cancelled by leaveView.
+func (m *model) viewContext() context.Context {
+	m.leaveView()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancelView = cancel
+	return ctx
+}
+
+// leaveView stops the work started by the view being left.
+func (m *model) leaveView() {
+	if m.cancelView != nil {
+		m.cancelView()
+		m.cancelView = nil
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	cmds := []tea.Cmd{watchConfig(m.configTime)}
+	if m.scan != nil {
+		cmds = append(cmds, waitForScan(m.scan))
+	}
+	if m.scanning {
+		cmds = append(cmds, scanTick())
+	}
+	if m.forwarded != nil {
+		cmds = append(cmds, waitForForward(m.forwarded))
+	}
+	return tea.Batch(cmds...)
 }
 
-func filterVideos(videos []string, filter string) []string {
+func filterVideos(videos []string, filter string, ix *index, st *state) []string {
 	if filter == "" {
 		return videos
 	}
 
-	lowerFilter := strings.ToLower(filter)
+	q := parseQuery(splitWords(filter))
 	var filtered []string
 	for _, video := range videos {
-		if strings.Contains(strings.ToLower(video), lowerFilter) {
+		if q.matches(video, ix, st) {
 			filtered = append(filtered, video)
 		}
 	}
 	return filtered
 }
 
+// sortVideos returns videos ordered by mode, videos that tie ordered by
+// then, and any still tied by path, so the order never depends on the order
+// they were found in. The "path" mode keeps the original walk order; the
+// column modes sort by what the column shows, with the most interesting
+// values (newest, largest, latest watched) first.
+func (m model) sortVideos(videos []string, mode, then string) []string {
+	sorted := append([]string(nil), videos...)
+	if mode == "path" {
+		return demoteJunk(sorted)
+	}
+	first, second := m.compareBy(mode, sorted), m.compareBy(then, sorted)
+	slices.SortFunc(sorted, func(a, b string) int {
+		if c := first(a, b); c != 0 {
+			return c
+		}
+		if c := second(a, b); c != 0 {
+			return c
+		}
+		return naturalCompare(a, b)
+	})
+	return demoteJunk(sorted)
+}
+
+// compareBy returns the comparison of the sort mode, for sorting videos. It
+// is always 0 for "path", which leaves ties to be broken by path.
+func (m model) compareBy(mode string, videos []string) func(a, b string) int {
+	// desc puts larger values first.
+	desc := func(key func(string) float64) func(a, b string) int {
+		return func(a, b string) int { return cmp.Compare(key(b), key(a)) }
+	}
+	switch mode {
+	case "rating":
+		return desc(func(video string) float64 { return float64(m.state.Ratings[video]) })
+	case "title":
+		return func(a, b string) int { return naturalCompare(m.label(a), m.label(b)) }
+	case "year":
+		return desc(func(video string) float64 { return float64(m.year(video)) })
+	case "score":
+		return desc(func(video string) float64 {
+			if md := m.index.metadata(video); md != nil {
+				return md.Rating
+			}
+			return 0
+		})
+	case "runtime":
+		return desc(func(video string) float64 {
+			if md := m.index.metadata(video); md != nil {
+				return float64(md.Runtime)
+			}
+			return 0
+		})
+	case "size":
+		return desc(func(video string) float64 { return float64(m.size(video)) })
+	case "added":
+		return desc(func(video string) float64 { return float64(m.modTime(video)) })
+	case "watched":
+		return func(a, b string) int { return m.state.Watched[b].Compare(m.state.Watched[a]) }
+	case "folder":
+		return func(a, b string) int { return naturalCompare(filepath.Dir(a), filepath.Dir(b)) }
+	case "episode":
+		// Episodes are grouped by show, in order, before everything else.
+		// Each show is in the order of showEpisodes.
+		episodes := make(map[string]episode, len(videos))
+		var eps []episode
+		for _, video := range videos {
+			if ep, ok := parseEpisode(video); ok {
+				episodes[video] = withScraped(ep, m.index)
+				eps = append(eps, episodes[video])
+			}
+		}
+		byAired := airedShows(eps)
+		return func(a, b string) int {
+			epA, okA := episodes[a]
+			epB, okB := episodes[b]
+			switch {
+			case okA != okB:
+				if okA {
+					return -1
+				}
+				return 1
+			case !okA:
+				return 0
+			}
+			return cmp.Or(strings.Compare(epA.show, epB.show), compareEpisodes(epA, epB, byAired[epA.show]))
+		}
+	}
+	return func(a, b string) int { return 0 }
+}
+
+// sortStatus describes the sort order, such as "Sorted by folder, then
+// added".
+func (m model) sortStatus() string {
+	status := "Sorted by " + sortModes[m.sortMode]
+	if m.thenMode != 0 && sortModes[m.sortMode] != "path" {
+		status += ", then " + sortModes[m.thenMode]
+	}
+	return status
+}
+
+// visibleVideos applies the filter, chosen root, hidden list and sort mode
+// to allVideos, with the pinned videos first and each film split across
+// files or found in several editions listed once (see groupVideos).
+func (m model) visibleVideos() []string {
+	videos := listOnce(listOnce(filterVideos(m.allVideos, m.filter, m.index, m.state), m.parts), m.editions)
+	if !m.showHidden || m.root != "" {
+		var visible []string
+		for _, video := range videos {
+			if (m.showHidden || !m.state.isHidden(video)) && (m.root == "" || rootOf(video) == m.root) {
+				visible = append(visible, video)
+			}
+		}
+		videos = visible
+	}
+	return m.state.pinnedFirst(m.sortVideos(videos, sortModes[m.sortMode], sortModes[m.thenMode]))
+}
+
+// scanStatus describes the running scan for the header.
+func (m model) scanStatus() string {
+	if m.progress == nil {
+		return "scanning..."
+	}
+	return "scanning: " + m.progress.String()
+}
+
+// refresh rebuilds the visible list from the current filter and sort mode,
+// keeping the cursor on the same video when it is still listed.
+func (m *model) refresh() {
+	var current string
+	if m.cursor < len(m.videos) {
+		current = m.videos[m.cursor]
+	}
+	m.groupVideos()
+	m.videos = m.visibleVideos()
+	m.cursor = 0
+	for i, video := range m.videos {
+		if video == current {
+			m.cursor = i
+			break
+		}
+	}
+	m.clampViewport()
+	if m.treeMode {
+		m.treeCursor = min(m.treeCursor, max(len(m.treeEntries(m.treeDir))-1, 0))
+	}
+}
+
+// groupVideos finds the films among allVideos split across files and those
+// found in several editions, each listed once.
+func (m *model) groupVideos() {
+	m.parts = multiPart(m.allVideos)
+	m.editions = m.editionsOf(listOnce(m.allVideos, m.parts))
+}
+
+// layout sizes the viewport to the terminal height, leaving room for the
+// header, status and detail lines.
+func (m *model) layout() {
+	if m.height == 0 {
+		return
+	}
+	// Besides the list there are the help, count, status and detail lines
+	// and a blank line above the detail.
+	m.viewportSize = m.height - 4 - strings.Count(m.help(), "\n") - 1
+	if detachPlayer {
+		// Leave room for the now-playing bar.
+		m.viewportSize--
+	}
+	if m.columns {
+		m.viewportSize--
+	}
+	m.viewportSize = max(m.viewportSize, 1)
+	m.clampViewport()
+}
+
+// The smallest terminal the launcher draws in. Anything smaller shows
+// viewTooSmall instead of a list too cramped to read.
+const (
+	minWidth  = 40
+	minHeight = 10
+)
+
+// listHelp sums up the keys of the list, those of listHelpCommands in the
+// place of each %s.
+const listHelp = "Video Browser - %s/%s, %s/%s, %s/%s (top/bottom), %s/%s (half page), counts (10%s), %s to filter, %s to tag, %s to rate, %s/%s to sort, %s to change names, %s for columns, %s for folders, %s for roots, %s to rescan a folder, %s for root health, %s for thumbnails, %s for the trailer, %s for Syncplay, %s to jump to a letter, %s for next episode, %s/%s to hide, %s to pin, %s for commands, %s to play, %s to quit"
+
+var listHelpCommands = []string{"up", "down", "page-up", "page-down", "top", "bottom", "half-page-down", "half-page-up", "down", "filter", "tag", "rate", "sort", "then-sort", "names", "columns", "folders", "roots", "rescan", "health", "thumbnails", "trailer", "syncplay", "jump", "next-episode", "hide", "hide-folder", "pin", "commands", "play", "quit"}
+
+// help is listHelp wrapped to the width of the terminal, or cut to one
+// line when wrapping it would take a quarter of the rows from the list.
+func (m model) help() string {
+	help := keys().help(listHelp, "list", listHelpCommands...)
+	if m.width == 0 {
+		return help
+	}
+	wrapped := ansi.Wrap(help, m.width, "")
+	if strings.Count(wrapped, "\n")+1 > max(m.height/4, 1) {
+		return m.fit(help)
+	}
+	return wrapped
+}
+
+// fit cuts line to the width of the terminal, so that it never wraps onto
+// the rows below.
+func (m model) fit(line string) string {
+	if m.width == 0 {
+		return line
+	}
+	return ansi.Truncate(line, m.width, "…")
+}
+
+// tooSmall reports whether the terminal is below minWidth×minHeight.
+func (m model) tooSmall() bool {
+	return m.width > 0 && (m.width < minWidth || m.height < minHeight)
+}
+
+// viewTooSmall asks for a larger window, cut to fit however small this
+// one is.
+func (m model) viewTooSmall() string {
+	lines := []string{
+		tr("Window too small"),
+		trf("%d×%d, needs %d×%d", m.width, m.height, minWidth, minHeight),
+	}
+	var b strings.Builder
+	for i, line := range lines[:min(len(lines), max(m.height, 1))] {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(ansi.Truncate(line, m.width, "…"))
+	}
+	return b.String()
+}
+
+func (m *model) clampViewport() {
+	if m.cursor < m.viewportTop {
+		m.viewportTop = m.cursor
+	}
+	if m.cursor >= m.viewportTop+m.viewportSize {
+		m.viewportTop = m.cursor - m.viewportSize + 1
+	}
+	if m.viewportTop < 0 {
+		m.viewportTop = 0
+	}
+}
+
+// rate stores a star rating for the selected video.
+func (m *model) rate(stars int) {
+	if len(m.videos) == 0 {
+		return
+	}
+	m.state.setRating(m.videos[m.cursor], stars)
+	if err := m.state.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving rating: %v", err)
+	}
+	m.index.track(m.videos[m.cursor])
+	if err := m.index.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving index: %v", err)
+	}
+}
+
+// hide hides or reveals target (the selected video or its folder).
+func (m *model) hide(target string) {
+	if m.state.toggleHidden(target) {
+		m.status = "Hidden " + target + " (. to show hidden)"
+	} else {
+		m.status = "Unhidden " + target
+	}
+	if err := m.state.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving hidden list: %v", err)
+	}
+	m.refresh()
+}
+
+// pin pins target (a video or folder) to the top of the list, the folder
+// view and the home screen, or unpins it if it is pinned.
+func (m *model) pin(target string) {
+	if m.state.togglePinned(target) {
+		m.status = "Pinned " + displayPath(target)
+	} else {
+		m.status = "Unpinned " + displayPath(target)
+	}
+	if err := m.state.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving pinned list: %v", err)
+	}
+	m.refresh()
+}
+
+// applyTags adds or removes the space-separated tags in input on the selected
+// video. Tags prefixed with "-" are removed.
+func (m *model) applyTags(input string) {
+	if len(m.videos) == 0 {
+		return
+	}
+	m.tagVideos([]string{m.videos[m.cursor]}, input)
+}
+
+// tagVideos adds or removes the tags in input on videos, as applyTags does.
+func (m *model) tagVideos(videos []string, input string) {
+	for _, video := range videos {
+		for _, tag := range strings.Fields(input) {
+			if strings.HasPrefix(tag, "-") {
+				m.index.removeTag(video, strings.TrimPrefix(tag, "-"))
+			} else {
+				m.index.addTag(video, tag)
+			}
+		}
+	}
+	if err := m.index.save(); err != nil {
+		m.status = fmt.Sprintf("Error saving tags: %v", err)
+	}
+}
+
+func renderTags(tags []string) string {
+	var chips []string
+	for _, tag := range tags {
+		h := fnv.New32a()
+		h.Write([]byte(tag))
+		color := tagColors[h.Sum32()%uint32(len(tagColors))]
+		chips = append(chips, lipgloss.NewStyle().Background(color).Foreground(lipgloss.Color("0")).Render("#"+tag))
+	}
+	return strings.Join(chips, " ")
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok && accessible {
+		before := m.selection()
+		next, cmd := m.update(msg)
+		return next, announce(before, next, cmd)
+	}
+	return m.update(msg)
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.viewportSize = msg.Height - 5
-		if m.viewportSize < 5 {
-			m.viewportSize = 5
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+	case playbackStartedMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error playing video: %v", msg.err)
+			return m, nil
+		}
+		m.playing = msg.pb
+		if msg.hookErr != nil {
+			m.status = msg.hookErr.Error()
+		}
+		cmds := []tea.Cmd{msg.pb.waitForExit(), publishCmd("play", msg.pb.video, m.index)}
+		if msg.pb.client != nil {
+			cmds = append(cmds, msg.pb.waitForEvent())
+		}
+		return m, tea.Batch(cmds...)
+	case scanBatchMsg:
+		if msg.replace {
+			m.allVideos, m.videos = nil, nil
+			clear(m.offline)
+		}
+		for _, root := range msg.offline {
+			m.offline[root] = true
+		}
+		m.addScanned(msg.videos)
+		m.restoreCursor()
+		if !msg.done {
+			return m, waitForScan(m.scan)
 		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error searching videos: %v", msg.err)
+		} else if len(m.allVideos) == 0 {
+			m.status = "No videos found matching your search."
+		} else if len(msg.offline) > 0 {
+			m.status = "Offline, listed from the cache: " + strings.Join(msg.offline, ", ")
+		} else if msg.replace {
+			m.status = "Library refreshed"
+		}
+		if m.dashboard {
+			m.sections = buildDashboard(m.videos, m.state, m.index)
+		}
+		return m, tea.Batch(m.findMoves(m.allVideos), checkFiles(m.ctx, m.allVideos))
+	case brokenMsg:
+		m.broken = msg
+	case trailerMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Trailer: %v", msg.err)
+			break
+		}
+		m.status = "Playing the trailer of " + msg.title
+		return m, playTrailer(msg)
+	case trailerDoneMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Player error: %v", msg.err)
+		}
+	case sheetMsg:
+		if !m.sheetMode || msg.video != m.sheetVideo {
+			break
+		}
+		if msg.err != nil {
+			m.sheetMode = false
+			m.status = fmt.Sprintf("Error making thumbnails: %v", msg.err)
+		} else {
+			m.sheet = msg.img
+		}
+	case healthMsg:
+		if m.healthMode {
+			m.health = msg
+		}
+	case scanTickMsg:
+		if m.scanning {
+			return m, scanTick()
+		}
+	case rescanMsg:
+		cmd := m.applyRescan(msg)
+		return m, cmd
+	case verifyMsg:
+		m.verifying = false
+		m.verifyErr = msg.err
+		if errors.Is(msg.err, context.Canceled) {
+			m.verifyErr = nil
+		}
+		if m.verifyErr == nil {
+			m.verifyReport = msg.report
+		}
+	case movesMsg:
+		m.applyMoves(msg)
+	case configMsg:
+		return m, tea.Batch(m.reloadConfig(msg), watchConfig(m.configTime))
+	case forwardedMsg:
+		m.applyForwarded(msg)
+		return m, waitForForward(m.forwarded)
+	case pluginActionMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		} else {
+			m.status = msg.message
+		}
+	case playbackErrorMsg:
+		m.status = fmt.Sprintf("Player error: %v", msg.err)
+	case screenshotMsg:
+		switch {
+		case msg.err != nil:
+			m.status = fmt.Sprintf("Screenshot failed: %v", msg.err)
+		case msg.path != "":
+			m.status = "Screenshot saved to " + msg.path
+		default:
+			m.status = "Screenshot saved"
+		}
+	case playbackEventMsg:
+		paused := msg.pb.paused
+		msg.pb.apply(msg.event)
+		cmds := []tea.Cmd{msg.pb.waitForEvent()}
+		if msg.pb.skip != nil {
+			if args := msg.pb.skip.handle(msg.event); args != nil {
+				cmds = append(cmds, msg.pb.send(args...))
+			}
+		}
+		if msg.pb.paused != paused {
+			event := "resume"
+			if msg.pb.paused {
+				event = "pause"
+			}
+			cmds = append(cmds, publishCmd(event, msg.pb.video, m.index))
+		}
+		return m, tea.Batch(cmds...)
+	case playbackExitedMsg:
+		if msg.end == endCrashed {
+			m.status = "The player failed on " + filepath.Base(msg.pb.video)
+			if msg.err != nil {
+				m.status += ": " + msg.err.Error()
+			}
+		}
+		if err := recordFinish(msg.pb.video, m.state, msg.end); err != nil {
+			m.status = fmt.Sprintf("Error saving state: %v", err)
+		}
+		notifyFinished(msg.pb.video, m.allVideos, m.state, m.index, msg.end)
+		if msg.pb.client != nil && m.index.rememberOptions(msg.pb.video, msg.pb.client.changedOptions()) {
+			if err := m.index.save(); err != nil {
+				m.status = fmt.Sprintf("Error saving index: %v", err)
+			}
+		}
+		if msg.pb.client != nil && m.state.rememberSpeed(msg.pb.video, msg.pb.client) {
+			if err := m.state.save(); err != nil {
+				m.status = fmt.Sprintf("Error saving state: %v", err)
+			}
+		}
+		if msg.hookErr != nil {
+			m.status = msg.hookErr.Error()
+		}
+		if m.playing == msg.pb {
+			m.playing = nil
+			m.nowPlaying = false
+			m.chapterMode = false
+		}
+		stopped := publishCmd("stop", msg.pb.video, m.index)
+		if m.queued != "" {
+			video := m.queued
+			m.queued = ""
+			next, cmd := m.start(video, m.queuedAudioOnly)
+			return next, tea.Batch(stopped, cmd)
+		}
+		return m, stopped
 	case tea.KeyMsg:
+		// Once the user starts moving, the previous session's selection is
+		// no longer wanted.
+		m.restore = ""
+		if m.confirm != nil {
+			return m.updateConfirm(msg)
+		}
 		if m.searchMode {
-			switch msg.String() {
-			case "enter":
+			switch keys().command("search", msg.String()) {
+			case "accept":
 				m.searchMode = false
-				m.videos = filterVideos(m.allVideos, m.searchInput.Value())
-				m.cursor = 0
-				m.viewportTop = 0
 				m.searchInput.Blur()
+				m.completer.suggestions = nil
 				return m, nil
-			case "esc", "ctrl+c":
+			case "cancel":
+				// Go back to the filter in use before editing.
 				m.searchMode = false
-				m.searchInput.SetValue("")
+				m.searchInput.SetValue(m.prevFilter)
 				m.searchInput.Blur()
+				m.completer.suggestions = nil
+				m.filter = m.prevFilter
+				m.refresh()
 				return m, nil
+			case "complete":
+				m.complete()
 			default:
 				m.searchInput, cmd = m.searchInput.Update(msg)
-				return m, cmd
+				m.suggest()
 			}
-		} else {
-			switch msg.String() {
-			case "ctrl+c", "q":
-				m.quitting = true
-				return m, tea.Quit
-			case "/":
-				m.searchMode = true
-				m.searchInput.Focus()
-				return m, textinput.Blink
-			case "up", "k":
-				if m.cursor > 0 {
-					m.cursor--
-					if m.cursor < m.viewportTop {
-						m.viewportTop = m.cursor
-					}
-				}
-			case "down", "j":
-				if m.cursor < len(m.videos)-1 {
-					m.cursor++
-					if m.cursor >= m.viewportTop+m.viewportSize {
-						m.viewportTop = m.cursor - m.viewportSize + 1
-					}
-				}
-			case "pgup":
-				m.cursor -= m.viewportSize
-				if m.cursor < 0 {
-					m.cursor = 0
-				}
-				m.viewportTop = m.cursor
-			case "pgdown":
-				m.cursor += m.viewportSize
-				if m.cursor >= len(m.videos) {
-					m.cursor = len(m.videos) - 1
-				}
-				if m.cursor >= m.viewportTop+m.viewportSize {
-					m.viewportTop = m.cursor - m.viewportSize + 1
-				}
-			case "home", "g":
-				m.cursor = 0
-				m.viewportTop = 0
-			case "end", "G":
-				m.cursor = len(m.videos) - 1
-				m.viewportTop = m.cursor - m.viewportSize + 1
-				if m.viewportTop < 0 {
-					m.viewportTop = 0
+			// The list follows the filter as it is typed.
+			if m.searchInput.Value() != m.filter {
+				m.filter = m.searchInput.Value()
+				m.refresh()
+			}
+			return m, cmd
+		} else if m.tagMode {
+			switch keys().command("tag", msg.String()) {
+			case "accept":
+				input, bulk := m.tagInput.Value(), m.bulkTag
+				m.tagMode, m.bulkTag = false, false
+				m.tagInput.SetValue("")
+				m.tagInput.Blur()
+				if bulk {
+					return m.tagAll(m.listedFiles(m.videos), input)
 				}
-			case "enter":
-				if len(m.videos) > 0 {
-					m.selected = m.videos[m.cursor]
+				m.applyTags(input)
+				return m, nil
+			case "cancel":
+				m.tagMode, m.bulkTag = false, false
+				m.tagInput.SetValue("")
+				m.tagInput.Blur()
+				return m, nil
+			default:
+				m.tagInput, cmd = m.tagInput.Update(msg)
+				return m, cmd
+			}
+		} else if m.playlistMode {
+			switch keys().command("playlist", msg.String()) {
+			case "accept":
+				m.playlistMode = false
+				m.playlistInput.Blur()
+				return m.exportPlaylist(m.listedFiles(m.videos), m.playlistInput.Value())
+			case "cancel":
+				m.playlistMode = false
+				m.playlistInput.Blur()
+				return m, nil
+			default:
+				m.playlistInput, cmd = m.playlistInput.Update(msg)
+				return m, cmd
+			}
+		} else if m.pinMode {
+			switch keys().command("pin", msg.String()) {
+			case "accept":
+				ok := m.pinInput.Value() == m.settings.PIN
+				m.pinMode = false
+				m.pinInput.SetValue("")
+				m.pinInput.Blur()
+				if ok {
 					m.quitting = true
 					return m, tea.Quit
 				}
+				m.status = "Wrong PIN"
+				return m, nil
+			case "cancel":
+				m.pinMode = false
+				m.pinInput.SetValue("")
+				m.pinInput.Blur()
+				return m, nil
+			default:
+				m.pinInput, cmd = m.pinInput.Update(msg)
+				return m, cmd
+			}
+		} else if m.paletteMode {
+			return m.updatePalette(msg)
+		} else if m.nowPlaying && m.playing != nil {
+			m.status = ""
+			return m.updateNowPlaying(msg)
+		} else if m.actionMode {
+			return m.updateActions(msg)
+		} else if m.editionMode {
+			return m.updateEditions(msg)
+		} else if m.rootMode {
+			return m.updateRoots(msg)
+		} else if m.treeMode {
+			return m.updateTree(msg)
+		} else if m.healthMode {
+			return m.updateHealth(msg)
+		} else if m.verifyMode {
+			return m.updateVerify(msg)
+		} else if m.sheetMode {
+			return m.updateSheet(msg)
+		} else if m.dashboard {
+			return m.updateDashboard(msg)
+		} else if m.rating {
+			m.rating = false
+			key := msg.String()
+			if key == "0" && len(m.videos) > 0 && m.state.Ratings[m.videos[m.cursor]] > 0 {
+				return m.ask("clear-rating", "Clear the rating?", func(m *model) tea.Cmd {
+					m.rate(0)
+					return nil
+				})
+			}
+			if len(key) == 1 && key[0] >= '1' && key[0] <= '5' {
+				m.rate(int(key[0] - '0'))
+			}
+			return m, nil
+		} else if m.jumping {
+			m.jumping = false
+			m.status = ""
+			if key := msg.String(); utf8.RuneCountInString(key) == 1 {
+				m.jumpTo(key)
 			}
+			return m, nil
+		} else {
+			key := msg.String()
+			if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (m.count != "" || key != "0") {
+				m.count += key
+				m.status = m.count
+				return m, nil
+			}
+			counted := m.count != ""
+			count := m.takeCount()
+			m.status = ""
+			return m.listCommand(keys().command("list", key), count, counted)
+		}
+	}
+	return m, nil
+}
+
+// listCommand runs command of the list (see bindings), count times for
+// the commands that move, or to row count for "bottom" when counted.
+func (m model) listCommand(command string, count int, counted bool) (tea.Model, tea.Cmd) {
+	switch command {
+	case "quit":
+		return m.quit()
+	case "filter":
+		m.searchMode = true
+		m.prevFilter = m.filter
+		m.searchInput.Focus()
+		return m, textinput.Blink
+	case "commands":
+		cmd := m.openPalette()
+		return m, cmd
+	case "tag":
+		if len(m.videos) > 0 {
+			m.tagMode = true
+			m.tagInput.Focus()
+			return m, textinput.Blink
+		}
+	case "rate":
+		if len(m.videos) > 0 {
+			m.rating = true
+			m.status = "Rate 1-5 stars (0 to clear)"
+		}
+	case "jump":
+		if len(m.videos) > 0 {
+			m.jumping = true
+			m.status = "Jump to the next video starting with..."
+		}
+	case "jump-again":
+		if m.lastJump != "" && len(m.videos) > 0 {
+			m.jumpTo(m.lastJump)
+		}
+	case "syncplay":
+		// Syncplay runs in the foreground even in detached mode.
+		if len(m.videos) > 0 && m.offline[rootOf(m.videos[m.cursor])] {
+			m.status = rootOf(m.videos[m.cursor]) + " is offline"
+		} else if len(m.videos) > 0 {
+			m.selected = m.videos[m.cursor]
+			m.syncplay = true
+			m.quitting = true
+			return m, tea.Quit
+		}
+	case "next-episode":
+		if len(m.videos) > 0 {
+			if next, ok := nextUpFor(m.videos[m.cursor], m.allVideos, m.state, m.index); ok {
+				return m.play(next)
+			}
+			m.status = "No next episode for this video"
+		}
+	case "now-playing":
+		if m.playing != nil && m.playing.client != nil {
+			m.nowPlaying = true
+		}
+	case "loudnorm":
+		cmd := m.toggleLoudnorm()
+		return m, cmd
+	case "screen":
+		m.cycleScreen()
+	case "actions":
+		if len(availableActions()) == 0 {
+			m.status = "No actions available (install plugins or ffmpeg)"
+		} else if len(m.videos) > 0 {
+			m.actionMode = true
+			m.actionCursor = 0
+		}
+	case "hide":
+		if len(m.videos) > 0 {
+			m.hide(m.videos[m.cursor])
+		}
+	case "hide-folder":
+		if len(m.videos) > 0 {
+			dir := filepath.Dir(m.videos[m.cursor])
+			if m.state.Hidden[dir] {
+				m.hide(dir)
+				break
+			}
+			return m.ask("hide-folder", "Hide everything in "+displayPath(dir)+"?", func(m *model) tea.Cmd {
+				m.hide(dir)
+				return nil
+			})
+		}
+	case "pin":
+		if len(m.videos) > 0 {
+			m.pin(m.videos[m.cursor])
+		}
+	case "folders":
+		m.openTree()
+	case "roots":
+		if len(videoDirs) < 2 {
+			m.status = "Only one library root is configured"
+		} else {
+			m.rootMode = true
+		}
+	case "trailer":
+		if len(m.videos) > 0 {
+			m.status = "Looking up the trailer..."
+			return m, findTrailer(m.ctx, m.videos[m.cursor], m.index)
+		}
+	case "thumbnails":
+		if len(m.videos) > 0 {
+			m.sheetMode = true
+			m.sheetVideo = m.videos[m.cursor]
+			m.sheet = nil
+			return m, loadSheet(m.viewContext(), m.sheetVideo)
+		}
+	case "health":
+		m.healthMode = true
+		m.health = nil
+		return m, checkRoots(m.viewContext())
+	case "rescan":
+		cmd := m.rescan()
+		return m, cmd
+	case "checksums":
+		m.verifyMode = true
+		if !m.verifying {
+			return m, loadVerifyReport
+		}
+	case "show-hidden":
+		m.showHidden = !m.showHidden
+		m.refresh()
+		if m.showHidden {
+			m.status = "Showing hidden videos"
+		} else {
+			m.status = "Hiding hidden videos"
+		}
+	case "sort":
+		m.sortMode = (m.sortMode + 1) % len(sortModes)
+		m.refresh()
+		m.status = m.sortStatus()
+	case "then-sort":
+		m.thenMode = (m.thenMode + 1) % len(sortModes)
+		m.refresh()
+		m.status = m.sortStatus()
+	case "names":
+		m.displayMode = (m.displayMode + 1) % len(displayModes)
+		m.labels = make(map[string]string, len(m.allVideos))
+		m.refresh()
+		m.status = "Showing " + displayModes[m.displayMode] + " names"
+	case "columns":
+		m.columns = !m.columns
+		m.layout()
+	case "up":
+		m.moveCursor(-count)
+	case "down":
+		m.moveCursor(count)
+	case "half-page-up":
+		m.scrollHalfPage(-count)
+	case "half-page-down":
+		m.scrollHalfPage(count)
+	case "page-up":
+		m.cursor -= m.viewportSize
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+		m.viewportTop = m.cursor
+	case "page-down":
+		m.cursor += m.viewportSize
+		if m.cursor >= len(m.videos) {
+			m.cursor = len(m.videos) - 1
+		}
+		if m.cursor >= m.viewportTop+m.viewportSize {
+			m.viewportTop = m.cursor - m.viewportSize + 1
+		}
+	case "top":
+		m.cursor = 0
+		m.viewportTop = 0
+	case "bottom":
+		if counted {
+			// As in vim, a count goes to that row instead.
+			m.moveCursor(count - 1 - m.cursor)
+			break
+		}
+		m.cursor = len(m.videos) - 1
+		m.viewportTop = m.cursor - m.viewportSize + 1
+		if m.viewportTop < 0 {
+			m.viewportTop = 0
+		}
+	case "play":
+		if len(m.videos) > 0 {
+			return m.playEdition(m.videos[m.cursor])
+		}
+	case "mark-all-watched", "tag-all", "export-playlist":
+		return m.bulkCommand(command)
+	case "listen":
+		if len(m.videos) > 0 {
+			return m.listen(m.videos[m.cursor])
 		}
 	}
 	return m, nil
 }
 
+// play starts video. Outside detached mode the TUI exits and main runs the
+// player; in detached mode the player starts in the background, replacing
+// whatever is currently playing.
+func (m model) play(video string) (tea.Model, tea.Cmd) {
+	return m.start(video, false)
+}
+
+// listen plays video without its picture.
+func (m model) listen(video string) (tea.Model, tea.Cmd) {
+	return m.start(video, true)
+}
+
+func (m model) start(video string, audioOnly bool) (tea.Model, tea.Cmd) {
+	if root := rootOf(video); m.offline[root] {
+		m.status = root + " is offline"
+		return m, nil
+	}
+	if !detachPlayer {
+		m.selected = video
+		m.audioOnly = audioOnly
+		m.quitting = true
+		return m, tea.Quit
+	}
+	if m.playing != nil {
+		m.queued = video
+		m.queuedAudioOnly = audioOnly
+		return m, m.playing.stop()
+	}
+	if err := recordStart(video, m.index, m.state); err != nil {
+		m.status = fmt.Sprintf("Error saving history: %v", err)
+	}
+	command := playerCommand
+	m.status = "Playing " + filepath.Base(video)
+	if audioOnly {
+		command = listenCommand
+		m.status = "Listening to " + filepath.Base(video)
+	}
+	return m, startDetached(video, hookEnv(video, m.index, m.state), optionArgs(m.index, m.state, video), command)
+}
+
+// quit leaves the launcher, asking for the PIN first on locked profiles.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if m.settings.locked() {
+		m.pinMode = true
+		m.pinInput.Focus()
+		return m, textinput.Blink
+	}
+	m.quitting = true
+	return m, tea.Quit
+}
+
 func (m model) View() string {
 	if m.quitting {
 		return ""
 	}
+	if m.tooSmall() {
+		return m.viewTooSmall()
+	}
+	if m.paletteMode {
+		return m.viewPalette()
+	}
+	if m.nowPlaying && m.playing != nil {
+		return m.viewNowPlaying()
+	}
+	if m.actionMode {
+		return m.viewActions()
+	}
+	if m.editionMode {
+		return m.viewEditions()
+	}
+	if m.rootMode {
+		return m.viewRoots()
+	}
+	if m.treeMode {
+		return m.viewTree()
+	}
+	if m.healthMode {
+		return m.viewHealth()
+	}
+	if m.verifyMode {
+		return m.viewVerify()
+	}
+	if m.sheetMode {
+		return m.viewSheet()
+	}
+	if m.dashboard && !m.pinMode {
+		return m.viewDashboard()
+	}
 
-	s := "Video Browser - arrows/jk, PgUp/PgDn, g/G (top/bottom), / to filter, Enter to play, q to quit\n"
-	s += fmt.Sprintf("Found %d videos (showing %d-%d)\n",
+	var b strings.Builder
+	b.WriteString(m.help() + "\n")
+	found := trf("Found %d videos (showing %d-%d)",
 		len(m.videos),
 		m.viewportTop+1,
 		min(m.viewportTop+m.viewportSize, len(m.videos)))
+	if m.scanning {
+		found += " - " + m.scanStatus()
+	}
+	b.WriteString(m.fit(found) + "\n")
 
 	if m.searchMode {
-		s += "/" + m.searchInput.View() + "\n"
+		b.WriteString("/" + m.searchInput.View() + m.viewSuggestions())
+	} else if m.tagMode {
+		b.WriteString("#" + m.tagInput.View())
+	} else if m.playlistMode {
+		b.WriteString(tr("Playlist:") + " " + m.playlistInput.View())
+	} else if m.pinMode {
+		b.WriteString(m.pinInput.View())
+	} else {
+		b.WriteString(m.fit(m.status))
+	}
+	b.WriteString("\n")
+
+	if m.columns {
+		m.viewColumns(&b)
 	} else {
-		s += "\n"
+		m.viewRows(&b)
+	}
+
+	if len(m.videos) > 0 {
+		detail := m.fit(m.renderDetail(m.videos[m.cursor]))
+		b.WriteString("\n" + detail + "\n")
 	}
+	if m.playing != nil {
+		b.WriteString(m.playing.view(m.width) + "\n")
+	}
+
+	// A final newline would take a row of its own and push the help off the
+	// top of a full screen.
+	return strings.TrimSuffix(b.String(), "\n")
+}
 
+// viewRows renders the visible part of the plain list, one path per row
+// followed by its rating and tags.
+func (m model) viewRows(b *strings.Builder) {
+	// Only the rows inside the viewport are rendered.
 	viewportEnd := min(m.viewportTop+m.viewportSize, len(m.videos))
 	for i := m.viewportTop; i < viewportEnd; i++ {
 		video := m.videos[i]
-		relPath, _ := filepath.Rel(videoDir, video)
+		var suffix string
+		if md := m.index.metadata(video); md != nil && md.Rating > 0 {
+			suffix += " " + scoreStyle.Render(fmt.Sprintf("%.1f", md.Rating))
+		}
+		if stars := m.state.Ratings[video]; stars > 0 {
+			suffix += " " + starStyle.Render(renderStars(stars))
+		}
+		if tags := m.index.tags(video); len(tags) > 0 {
+			suffix += " " + renderTags(tags)
+		}
+		if m.offline[rootOf(video)] {
+			suffix += " " + hiddenStyle.Render("offline")
+		}
+		if m.state.isPinned(video) {
+			suffix += " " + hiddenStyle.Render("pinned")
+		}
+		if parts := len(m.parts[video]); parts > 0 {
+			suffix += " " + hiddenStyle.Render(fmt.Sprintf("%d parts", parts))
+		}
+		if editions := len(m.editions[video]); editions > 0 {
+			suffix += " " + hiddenStyle.Render(fmt.Sprintf("%d editions", editions))
+		}
+		if badge := m.brokenBadge(video); badge != "" {
+			suffix += " " + badge
+		}
+		// Shorten the path rather than letting the row wrap.
+		label := videoIcon(video) + m.label(video)
+		if m.width > 0 {
+			label = truncateMiddle(label, max(m.width-lipgloss.Width(suffix), 10))
+		}
 		if m.cursor == i {
-			s += selectedStyle.Render(relPath) + "\n"
+			b.WriteString(selectedStyle.Render(label))
+		} else if m.offline[rootOf(video)] || m.showHidden && m.state.isHidden(video) || isJunk(video) {
+			b.WriteString(hiddenStyle.Render(label))
 		} else {
-			s += relPath + "\n"
+			b.WriteString(label)
 		}
+		b.WriteString(suffix + "\n")
 	}
+}
 
-	return s
+// label returns the cached display string of video in the current display
+// mode.
+func (m model) label(video string) string {
+	label, ok := m.labels[video]
+	if !ok {
+		switch displayModes[m.displayMode] {
+		case "absolute":
+			label = video
+		case "title":
+			label = videoTitle(video, m.index)
+		default:
+			label = displayPath(video)
+		}
+		m.labels[video] = label
+	}
+	return label
+}
+
+// renderDetail describes the selected video below the list.
+func (m model) renderDetail(video string) string {
+	detail := filepath.Base(video)
+	if md := m.index.metadata(video); md != nil && md.Title != "" {
+		detail = md.Title
+		if md.Year > 0 {
+			detail += fmt.Sprintf(" (%d)", md.Year)
+		}
+		if md.Certification != "" {
+			detail += "  [" + md.Certification + "]"
+		}
+		if len(md.Genres) > 0 {
+			detail += "  " + strings.Join(md.Genres, ", ")
+		}
+	}
+	stars := m.state.Ratings[video]
+	if stars > 0 {
+		detail += "  " + starStyle.Render(renderStars(stars))
+	} else {
+		detail += "  " + tr("unrated")
+	}
+	if tags := m.index.tags(video); len(tags) > 0 {
+		detail += "  " + renderTags(tags)
+	}
+	if badge := m.brokenBadge(video); badge != "" {
+		detail += "  " + badge
+	}
+	return detail
+}
+
+// displayPath shows video relative to its library root, or in full when it
+// lives elsewhere (such as URLs from source plugins). With several roots the
+// root's name is kept in front.
+func displayPath(video string) string {
+	root := rootOf(video)
+	if root == "" {
+		return video
+	}
+	rel, err := filepath.Rel(root, video)
+	if err != nil {
+		return video
+	}
+	if len(videoDirs) > 1 {
+		rel = filepath.Join(rootName(root), rel)
+	}
+	return rel
 }
 
 func min(a, b int) int {
@@ -240,7 +1413,46 @@ func min(a, b int) int {
 }
 
 func main() {
-	if videoDir == "" {
+	flag.StringVar(&profileName, "profile", profileName, "profile whose history and ratings are used")
+	flag.BoolVar(&showHidden, "show-hidden", showHidden, "include hidden videos in the list")
+	flag.BoolVar(&detachPlayer, "detach", detachPlayer, "keep the list open while the player runs")
+	flag.BoolVar(&notifications, "notify", notifications, "send desktop notifications when playback starts and ends")
+	flag.BoolVar(&accessible, "accessible", accessible, "work with screen readers: no alternate screen or reverse video, and announce the selection")
+	flag.StringVar(&dataDirFlag, "data-dir", "", "directory for the index and profiles (default $XDG_DATA_HOME/movie-launcher)")
+	flag.StringVar(&cacheDirFlag, "cache-dir", "", "directory for the library cache (default $XDG_CACHE_HOME/movie-launcher)")
+	flag.StringVar(&configDirFlag, "config-dir", "", "directory holding config.toml and plugins (default $XDG_CONFIG_HOME/movie-launcher)")
+	logFile := flag.String("log-file", "", "write diagnostics to this file")
+	verbose := flag.Bool("verbose", false, "log debug details such as skipped files and IPC traffic")
+	followSymlinks := flag.Bool("follow-symlinks", false, "descend into symlinked directories")
+	skipNetwork := flag.Bool("skip-network-mounts", false, "skip directories on network filesystems")
+	targetName := flag.String("target", "", "play on this [[target]] from the config over SSH")
+	window := flag.String("window", "", "start the player fullscreen or windowed")
+	screen := flag.Int("screen", -1, "open the player on this display (0 is the first)")
+	images := flag.Bool("images", false, "list photos too and show them as a slideshow")
+	flag.BoolVar(&pathOnly, "path-only", pathOnly, "match keywords against file paths only, not titles, plots, genres, actors or tags")
+	stdinList := flag.Bool("stdin", false, "list the paths read from stdin instead of scanning the library")
+	dmenu := flag.Bool("dmenu", false, "print titles for dmenu/rofi/fuzzel and play the one read back from stdin")
+	newInstance := flag.Bool("new-instance", false, "start a separate launcher even if one is running for the profile")
+	fits := flag.String("fits", "", "list unwatched videos no longer than this (e.g. 100m), best rated first")
+	reindex := flag.Bool("reindex", false, "walk the library instead of using the cached file list")
+	resume := flag.Bool("resume-session", false, "reopen the results of the previous keyword search without scanning")
+	flag.Usage = func() {
+		fmt.Println("Usage: movie-launcher [--profile name] [--data-dir dir] [--cache-dir dir] [--config-dir dir] [--show-hidden] [--detach] [--notify] [--log-file path] [--verbose] [--follow-symlinks] [--skip-network-mounts] [--images] [--target name] [--resume-session] [--new-instance] [--reindex] [--fits 100m] [--path-only] [--dmenu] [--stdin] [search keywords...]")
+		fmt.Println("Example: movie-launcher matrix 1999")
+	}
+	flag.Parse()
+
+	var budget int
+	if *fits != "" {
+		var err error
+		if budget, err = parseBudget(*fits); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	videoDirs = parseRoots(videoDir)
+	if len(videoDirs) == 0 && !*stdinList {
 		fmt.Println("VIDEO_DIR environment variable is required")
 		os.Exit(1)
 	}
@@ -248,43 +1460,301 @@ func main() {
 		videoPlayer = "mpv"
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: movie-launcher <search keywords...>")
-		fmt.Println("Example: movie-launcher matrix 1999")
+	if !validProfileName(profileName) {
+		fmt.Printf("Invalid profile name: %q\n", profileName)
 		os.Exit(1)
 	}
 
-	keywords := os.Args[1:]
-	fmt.Printf("Searching for videos matching: %s\n", strings.Join(keywords, " "))
+	logCloser, err := setupLogging(*logFile, *verbose)
+	if err != nil {
+		fmt.Printf("Error opening log file: %v\n", err)
+		os.Exit(1)
+	}
+	defer logCloser.Close()
 
-	videos, err := searchVideos(keywords)
+	applyConfigFlags = func(c *config) error {
+		if *followSymlinks {
+			c.FollowSymlinks = true
+		}
+		if *skipNetwork {
+			c.SkipNetworkMounts = true
+		}
+		if *images {
+			c.Images = true
+		}
+		if *targetName != "" {
+			c.DefaultTarget = *targetName
+		}
+		if *window != "" {
+			if *window != "fullscreen" && *window != "windowed" {
+				return fmt.Errorf("--window: want fullscreen or windowed, not %q", *window)
+			}
+			c.Window = *window
+		}
+		if *screen >= 0 {
+			c.Screen = screen
+		}
+		var t *target
+		if c.DefaultTarget != "" {
+			var err error
+			if t, err = findTarget(*c, c.DefaultTarget); err != nil {
+				return err
+			}
+		}
+		remoteTarget = t
+		return nil
+	}
+	configTime := configModTime()
+	cfg, err = loadConfig(configPath())
+	if err == nil {
+		err = applyConfigFlags(&cfg)
+	}
 	if err != nil {
-		fmt.Printf("Error searching videos: %v\n", err)
+		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	setLanguage(cfg.Language)
+	accessible = accessible || cfg.Accessible
+	if accessible {
+		useAccessibleStyles()
+	}
+
+	var pluginErrs []error
+	plugins, pluginErrs = loadPlugins(pluginDir())
+	for _, err := range pluginErrs {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	keywords := flag.Args()
+
+	// Ctrl+C stops subcommands and background work cleanly; a second one
+	// kills the launcher as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if len(keywords) > 0 && keywords[0] == "bench" {
+		if err := bench(keywords[1:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	if len(videos) == 0 {
-		fmt.Println("No videos found matching your search.")
-		os.Exit(0)
+	ix, err := loadIndex(filepath.Join(dataDir(), "index.json"))
+	if err != nil {
+		fmt.Printf("Error loading index: %v\n", err)
+		os.Exit(1)
 	}
 
-	p := tea.NewProgram(initialModel(videos), tea.WithAltScreen())
-	m, err := p.Run()
+	st, err := loadState(filepath.Join(profileDir(), "state.json"))
 	if err != nil {
-		fmt.Printf("Error running UI: %v\n", err)
+		fmt.Printf("Error loading state: %v\n", err)
 		os.Exit(1)
 	}
+	// exit leaves with code, syncing the state first when it is shared,
+	// since os.Exit skips the deferred sync.
+	exit := os.Exit
+	if cfg.SyncFile != "" {
+		syncState(st)
+		// Synced again on the way out, with what changed meanwhile.
+		syncBack := func() {
+			syncState(st)
+			if err := st.save(); err != nil {
+				logger.Warn("saving state failed", "err", err)
+			}
+		}
+		defer syncBack()
+		exit = func(code int) {
+			syncBack()
+			os.Exit(code)
+		}
+	}
+
+	settings, err := loadProfileSettings()
+	if err != nil {
+		fmt.Printf("Error loading profile settings: %v\n", err)
+		exit(1)
+	}
+
+	if len(keywords) > 0 && keywords[0] == "scrape" {
+		if err := scrape(ctx, keywords[1:], ix, st); err != nil {
+			fmt.Printf("Error scraping metadata: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(keywords) > 0 && keywords[0] == "marathon" {
+		if err := marathon(ctx, keywords[1:], ix, st, settings); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(keywords) > 0 && keywords[0] == "import" {
+		if err := importWatchState(ctx, keywords[1:], ix, st); err != nil {
+			fmt.Printf("Error importing: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(keywords) > 0 && keywords[0] == "export" {
+		if err := exportWatchState(ctx, keywords[1:], ix, st); err != nil {
+			fmt.Printf("Error exporting: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(keywords) > 0 && keywords[0] == "verify" {
+		if err := verifyChecksums(ctx, keywords[1:], ix, st); err != nil {
+			fmt.Printf("Error verifying checksums: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(keywords) == 2 && keywords[0] == "index" && keywords[1] == "verify" {
+		if err := verifyIndex(ix); err != nil {
+			fmt.Printf("Error verifying index: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if *dmenu {
+		if err := runDmenu(ctx, keywords, ix, st, settings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	// Results stream into the list while the library is scanned. A
+	// PIN-locked profile returns to the list after playback so the launcher
+	// can only be left by entering the PIN; later rounds reuse the results.
+	// Only one launcher runs per profile: a second one hands its keywords
+	// to the first and exits. --new-instance leaves both alone.
+	var forwarded <-chan controlRequest
+	if !*newInstance {
+		if !*resume && !*stdinList && *fits == "" && forward(keywords) {
+			fmt.Println("Sent the search to the launcher already running")
+			return
+		}
+		if l, ch, err := listenControl(); err != nil {
+			logger.Warn("control socket unavailable", "err", err)
+		} else {
+			defer l.Close()
+			defer os.Remove(controlSocket())
+			forwarded = ch
+		}
+	}
 
-	finalModel := m.(model)
-	if finalModel.selected != "" {
-		fmt.Printf("Playing: %s\n", finalModel.selected)
-		cmd := exec.Command(videoPlayer, finalModel.selected)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-		if err := cmd.Run(); err != nil {
+	var videos []string
+	scanned := false
+	var listed <-chan scanBatchMsg
+	if *stdinList {
+		list, err := readList(os.Stdin)
+		if err != nil {
+			fmt.Printf("Error reading the list from stdin: %v\n", err)
+			exit(1)
+		}
+		listed = scanList(list)
+	}
+	if *resume {
+		if st.Session == nil || len(st.Session.Keywords) == 0 {
+			fmt.Println("No previous search to resume")
+			exit(1)
+		}
+		keywords = st.Session.Keywords
+		// Filtered as a scan would be, in case the profile was restricted
+		// since.
+		videos = slices.DeleteFunc(st.Session.results(), func(video string) bool { return !settings.allows(video, ix) })
+		scanned = true
+	}
+	for {
+		roundCtx, cancelRound := context.WithCancel(ctx)
+		initial := initialModel(videos, ix, st, settings)
+		initial.ctx = roundCtx
+		initial.query = parseQuery(keywords)
+		initial.query.metadata = !pathOnly
+		initial.query.fits = budget
+		initial.forwarded = forwarded
+		initial.configTime = configTime
+		if !scanned && listed != nil {
+			initial.scan = listed
+			scanned = true
+		}
+		if !scanned {
+			initial.scan, initial.progress = startScan(roundCtx, *reindex)
+			initial.scanning = true
+			scanned = true
+		}
+		initial.dashboard = len(keywords) == 0 && budget == 0
+		if st.Session != nil {
+			initial.restoreSession(st.Session)
+			initial.dashboard = initial.dashboard && st.Session.Dashboard
+		}
+		if budget > 0 {
+			initial.sortMode = slices.Index(sortModes, "score")
+			initial.refresh()
+		}
+		if initial.dashboard {
+			initial.sections = buildDashboard(initial.videos, st, ix)
+		}
+		var opts []tea.ProgramOption
+		if !accessible {
+			opts = append(opts, tea.WithAltScreen())
+		}
+		if *stdinList {
+			// Keys come from the terminal since stdin held the list.
+			opts = append(opts, tea.WithInputTTY())
+		}
+		finalModel, crashed, err := runSafely(initial, opts...)
+		cancelRound()
+		if crashed != nil {
+			reportCrash(crashed)
+			exit(2)
+		}
+		if err != nil {
+			fmt.Printf("Error running UI: %v\n", err)
+			exit(1)
+		}
+
+		videos = finalModel.allVideos
+		st.Session = finalModel.session()
+		if len(keywords) > 0 {
+			st.Session.Keywords = keywords
+			st.Session.Results = videos
+		}
+		if err := st.save(); err != nil {
+			fmt.Printf("Error saving session: %v\n", err)
+		}
+		if finalModel.selected == "" {
+			return
+		}
+		command := playerCommand
+		if finalModel.syncplay {
+			command = syncplayCommand
+			fmt.Printf("Watching with Syncplay: %s\n", finalModel.selected)
+		} else if finalModel.audioOnly {
+			command = listenCommand
+			fmt.Printf("Listening to: %s\n", finalModel.selected)
+		} else {
+			fmt.Printf("Playing: %s\n", finalModel.selected)
+		}
+		err = playAnnounced(finalModel.selected, videos, ix, st, command)
+		if err != nil {
 			fmt.Printf("Error playing video: %v\n", err)
-			os.Exit(1)
+			if !settings.locked() {
+				exit(1)
+			}
+		}
+		if !settings.locked() {
+			return
 		}
 	}
 }
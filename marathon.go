@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// buildMarathon picks unwatched videos with a known runtime from videos,
+// best community rating first, until no other fits in budget minutes.
+func buildMarathon(videos []string, budget int, ix *index, st *state) ([]string, int) {
+	var candidates []string
+	for _, video := range videos {
+		if md := ix.metadata(video); md != nil && md.Runtime > 0 && st.Watched[video].IsZero() {
+			candidates = append(candidates, video)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return ix.metadata(candidates[i]).Rating > ix.metadata(candidates[j]).Rating
+	})
+	var queue []string
+	total := 0
+	for _, video := range candidates {
+		if runtime := ix.metadata(video).Runtime; total+runtime <= budget {
+			queue = append(queue, video)
+			total += runtime
+		}
+	}
+	return queue, total
+}
+
+// marathon builds a queue of videos matching keywords that fills the time
+// budget in args[0], such as "3h", and plays it back to back. Stopping a
+// video before its end stops the marathon.
+func marathon(ctx context.Context, args []string, ix *index, st *state, settings profileSettings) error {
+	if len(args) == 0 {
+		return errors.New("usage: movie-launcher marathon <time budget> [keywords...]")
+	}
+	if settings.locked() {
+		return errors.New("the profile is locked with a PIN")
+	}
+	budget, err := parseBudget(args[0])
+	if err != nil {
+		return err
+	}
+	found, err := searchVideos(ctx, args[1:], ix, st)
+	if err != nil {
+		return err
+	}
+	var videos []string
+	for _, video := range found {
+		if settings.allows(video, ix) && !st.isHidden(video) {
+			videos = append(videos, video)
+		}
+	}
+	queue, total := buildMarathon(videos, budget, ix, st)
+	if len(queue) == 0 {
+		return fmt.Errorf("no unwatched video with a known runtime fits in %s", formatRuntime(budget))
+	}
+	fmt.Printf("Marathon of %d videos, %s:\n", len(queue), formatRuntime(total))
+	for i, video := range queue {
+		fmt.Printf("%2d. %s  %s\n", i+1, videoTitle(video, ix), formatRuntime(ix.metadata(video).Runtime))
+	}
+	for i, video := range queue {
+		fmt.Printf("Playing: %s\n", videoTitle(video, ix))
+		if err := playAnnounced(video, videos, ix, st, playerCommand); err != nil {
+			return err
+		}
+		if st.Watched[video].IsZero() && i < len(queue)-1 {
+			fmt.Printf("Stopped the marathon with %d videos left\n", len(queue)-i-1)
+			return nil
+		}
+	}
+	return nil
+}
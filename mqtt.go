@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mqttTimeout bounds connecting to the broker and publishing one event.
+const mqttTimeout = 3 * time.Second
+
+// mqttConfig is the [mqtt] table of config.toml. When Broker is set, play,
+// pause, resume and stop events are published to Topic, e.g. for Home
+// Assistant automations.
+type mqttConfig struct {
+	// Broker is "host:port" (port 1883 when left out).
+	Broker   string `toml:"broker"`
+	Topic    string `toml:"topic"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// mediaEvent is the JSON payload of a published event.
+type mediaEvent struct {
+	Event   string `json:"event"`
+	File    string `json:"file"`
+	Title   string `json:"title"`
+	Year    int    `json:"year,omitempty"`
+	Profile string `json:"profile"`
+}
+
+// publishEvent sends event about video to the configured MQTT topic. It
+// does nothing when no broker is configured.
+func publishEvent(event, video string, ix *index) error {
+	mc := cfg.MQTT
+	if mc.Broker == "" {
+		return nil
+	}
+	title, year := cleanTitle(video)
+	if md := ix.metadata(video); md != nil && md.Title != "" {
+		title, year = md.Title, md.Year
+	}
+	payload, err := json.Marshal(mediaEvent{event, video, title, year, profileName})
+	if err != nil {
+		return err
+	}
+	topic := mc.Topic
+	if topic == "" {
+		topic = "movie-launcher"
+	}
+	logger.Info("publishing event", "event", event, "topic", topic)
+	return mqttPublish(mc, topic, payload)
+}
+
+// publishCmd publishes an event in the background, logging failures since
+// the player should not be held up by home automation.
+func publishCmd(event, video string, ix *index) tea.Cmd {
+	if cfg.MQTT.Broker == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := publishEvent(event, video, ix); err != nil {
+			logger.Warn("publishing event failed", "event", event, "err", err)
+		}
+		return nil
+	}
+}
+
+// mqttPublish connects to the broker, publishes payload to topic with QoS 0
+// and disconnects again (MQTT 3.1.1, plain TCP).
+func mqttPublish(mc mqttConfig, topic string, payload []byte) error {
+	addr := strings.TrimPrefix(mc.Broker, "tcp://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "1883")
+	}
+	conn, err := net.DialTimeout("tcp", addr, mqttTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+
+	// CONNECT with a clean session and a 60 second keep-alive.
+	flags := byte(0x02)
+	body := append(mqttString("MQTT"), 4, 0, 0, 60)
+	body = append(body, mqttString(fmt.Sprintf("movie-launcher-%d", os.Getpid()))...)
+	if mc.Username != "" {
+		flags |= 0x80
+		body = append(body, mqttString(mc.Username)...)
+		if mc.Password != "" {
+			flags |= 0x40
+			body = append(body, mqttString(mc.Password)...)
+		}
+	}
+	body[7] = flags
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		return err
+	}
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, connack); err != nil {
+		return err
+	}
+	if connack[0] != 0x20 || connack[3] != 0 {
+		return fmt.Errorf("broker refused the connection (code %d)", connack[3])
+	}
+
+	if _, err := conn.Write(mqttPacket(0x30, append(mqttString(topic), payload...))); err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte{0xe0, 0})
+	return err
+}
+
+// mqttPacket frames body with the packet type and its variable-length size.
+func mqttPacket(kind byte, body []byte) []byte {
+	packet := []byte{kind}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString encodes s with its two-byte length prefix.
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
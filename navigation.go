@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jumpTo moves the cursor to the next video after it whose label starts with
+// letter (ignoring case), wrapping around, so repeating the jump cycles through the matches.
+func (m *model) jumpTo(letter string) {
+	m.lastJump = letter
+	for step := 1; step <= len(m.videos); step++ {
+		i := (m.cursor + step) % len(m.videos)
+		first, _ := utf8.DecodeRuneInString(m.label(m.videos[i]))
+		if strings.EqualFold(string(first), letter) {
+			m.cursor = i
+			m.clampViewport()
+			return
+		}
+	}
+	m.status = "No video starting with " + letter
+}
+
+// moveCursor moves the cursor by delta rows, stopping at either end of the
+// list, and scrolls just enough to keep it visible.
+func (m *model) moveCursor(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.videos)-1))
+	m.clampViewport()
+}
+
+// scrollHalfPage moves both the cursor and the viewport by half a page per
+// count, like Ctrl+D and Ctrl+U in vim.
+func (m *model) scrollHalfPage(count int) {
+	delta := count * max(m.viewportSize/2, 1)
+	m.viewportTop = max(0, min(m.viewportTop+delta, len(m.videos)-m.viewportSize))
+	m.moveCursor(delta)
+}
+
+// takeCount returns the pending count prefix (1 when none was typed) and
+// clears it.
+func (m *model) takeCount() int {
+	count, err := strconv.Atoi(m.count)
+	m.count = ""
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
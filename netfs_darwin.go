@@ -0,0 +1,26 @@
+package main
+
+import "syscall"
+
+var networkFSTypes = map[string]bool{
+	"nfs":    true,
+	"smbfs":  true,
+	"afpfs":  true,
+	"webdav": true,
+	"cifs":   true,
+}
+
+func isNetworkFS(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	name := make([]byte, 0, len(st.Fstypename))
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return networkFSTypes[string(name)]
+}
@@ -0,0 +1,24 @@
+package main
+
+import "syscall"
+
+// networkFSMagic lists statfs f_type values of network filesystems.
+var networkFSMagic = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517b:     true, // SMB
+	0xff534d42: true, // CIFS
+	0xfe534d42: true, // SMB2
+	0x5346414f: true, // AFS
+	0x73757245: true, // Coda
+	0x564c:     true, // NCP
+	0x01021997: true, // 9P
+	0x00c36400: true, // Ceph
+}
+
+func isNetworkFS(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	return networkFSMagic[uint32(st.Type)]
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// isNetworkFS cannot tell filesystems apart on this platform.
+func isNetworkFS(path string) bool {
+	return false
+}
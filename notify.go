@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// sendNotification shows a desktop notification using notify-send, or
+// osascript on macOS. Failures are ignored since notifications are a
+// convenience only.
+func sendNotification(title, body string) {
+	if !notifications {
+		return
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// Passed as arguments, since Go's quoting is not AppleScript's.
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 1 of argv) with title (item 2 of argv)",
+			"-e", "end run",
+			body, title)
+	} else {
+		cmd = exec.Command("notify-send", "--app-name=movie-launcher", title, body)
+	}
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+}
+
+func notifyStarted(video string) {
+	sendNotification("Now playing", filepath.Base(video))
+}
+
+// notifyFinished announces the end of video, naming the next episode when
+// one is queued up for its show.
+func notifyFinished(video string, videos []string, st *state, ix *index, end playbackEnd) {
+	if end != endFinished {
+		return
+	}
+	body := filepath.Base(video)
+	if next, ok := nextUpFor(video, videos, st, ix); ok {
+		body += "\nUp next: " + filepath.Base(next)
+	}
+	sendNotification("Finished", body)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateNowPlaying handles keys in the now-playing view, which controls the
+// detached mpv instance over IPC.
+func (m model) updateNowPlaying(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pb := m.playing
+	if m.chapterMode {
+		switch keys().command("chapters", msg.String()) {
+		case "up":
+			if m.chapterCursor > 0 {
+				m.chapterCursor--
+			}
+		case "down":
+			if m.chapterCursor < len(pb.chapters)-1 {
+				m.chapterCursor++
+			}
+		case "jump":
+			m.chapterMode = false
+			return m, pb.send("set_property", "chapter", m.chapterCursor)
+		case "close":
+			m.chapterMode = false
+		}
+		return m, nil
+	}
+
+	switch keys().command("now-playing", msg.String()) {
+	case "close":
+		m.nowPlaying = false
+	case "stop":
+		m.nowPlaying = false
+		return m, pb.stop()
+	case "pause":
+		return m, pb.send("cycle", "pause")
+	case "seek-back":
+		return m, pb.send("seek", -10)
+	case "seek-forward":
+		return m, pb.send("seek", 10)
+	case "seek-back-more":
+		return m, pb.send("seek", -60)
+	case "seek-forward-more":
+		return m, pb.send("seek", 60)
+	case "previous-chapter":
+		return m, pb.send("add", "chapter", -1)
+	case "next-chapter":
+		return m, pb.send("add", "chapter", 1)
+	case "audio-track":
+		return m, pb.send("cycle", "audio")
+	case "subtitles":
+		return m, pb.send("cycle", "sub")
+	case "screenshot":
+		return m, pb.screenshot()
+	case "loudnorm":
+		cmd := m.toggleLoudnorm()
+		return m, cmd
+	case "faster":
+		return m, pb.send("set_property", "speed", nextSpeed(pb.currentSpeed(), 1))
+	case "slower":
+		return m, pb.send("set_property", "speed", nextSpeed(pb.currentSpeed(), -1))
+	case "normal-speed":
+		return m, pb.send("set_property", "speed", 1)
+	case "ab-loop":
+		// Sets A, then B, then clears the loop.
+		return m, pb.send("ab-loop")
+	case "loop":
+		return m, pb.send("cycle-values", "loop-file", "inf", "no")
+	case "subtitles-earlier":
+		return m, pb.send("add", "sub-delay", -0.1)
+	case "subtitles-later":
+		return m, pb.send("add", "sub-delay", 0.1)
+	case "audio-earlier":
+		return m, pb.send("add", "audio-delay", -0.1)
+	case "audio-later":
+		return m, pb.send("add", "audio-delay", 0.1)
+	case "chapters":
+		if len(pb.chapters) > 0 {
+			m.chapterMode = true
+			m.chapterCursor = max(pb.chapter, 0)
+		}
+	}
+	return m, nil
+}
+
+// toggleLoudnorm switches loudness normalization for the rest of the
+// session, including the video playing now.
+func (m *model) toggleLoudnorm() tea.Cmd {
+	loudnormToggled = !loudnormToggled
+	m.status = "Loudness normalization off"
+	if loudnormEnabled() {
+		m.status = "Loudness normalization on"
+	}
+	if m.playing != nil {
+		return m.playing.send("af", "toggle", loudnormFilter)
+	}
+	return nil
+}
+
+func (m model) viewNowPlaying() string {
+	pb := m.playing
+	s := keys().help("Now Playing - %s pause, %s/%s 10s, %s/%s 1m, %s/%s chapter, %s chapters, %s audio, %s subs, %s screenshot, %s A-B loop, %s loop, %s/%s speed, %s loudnorm, %s/%s sub delay, %s/%s audio delay, %s stop, %s back", "now-playing",
+		"pause", "seek-back", "seek-forward", "seek-forward-more", "seek-back-more", "previous-chapter", "next-chapter", "chapters", "audio-track", "subtitles", "screenshot", "ab-loop", "loop", "slower", "faster", "loudnorm", "subtitles-earlier", "subtitles-later", "audio-earlier", "audio-later", "stop", "close") + "\n\n"
+	s += pb.title + "\n"
+	s += fmt.Sprintf("%s / %s", formatSeconds(pb.position), formatSeconds(pb.duration))
+	if speed := pb.currentSpeed(); speed != 1 {
+		s += fmt.Sprintf("  %g×", speed)
+	}
+	s += "\n"
+	s += progressBar(pb.position, pb.duration, max(m.width-2, 10)) + "\n"
+	if pb.chapter >= 0 && pb.chapter < len(pb.chapters) {
+		s += trf("Chapter %d/%d: %s", pb.chapter+1, len(pb.chapters), pb.chapters[pb.chapter].Title) + "\n"
+	}
+	s += trf("Audio: %s  Subtitles: %s", pb.selectedTrack("audio"), pb.selectedTrack("sub")) + "\n"
+	if pb.subDelay != 0 || pb.audioDelay != 0 {
+		s += trf("Subtitle delay: %+.1fs  Audio delay: %+.1fs", pb.subDelay, pb.audioDelay) + "\n"
+	}
+	switch {
+	case pb.loopA >= 0 && pb.loopB >= 0:
+		s += trf("A-B loop: %s - %s", formatSeconds(pb.loopA), formatSeconds(pb.loopB)) + "\n"
+	case pb.loopA >= 0:
+		s += trf("A-B loop: from %s, press %s again to set B", formatSeconds(pb.loopA), keys().label("now-playing", "ab-loop")) + "\n"
+	case pb.loopFile:
+		s += tr("Looping") + "\n"
+	}
+	if pb.paused {
+		s += tr("(paused)") + "\n"
+	}
+
+	if m.chapterMode {
+		s += "\n" + keys().help("Chapters - %s to jump, %s to close", "chapters", "jump", "close") + "\n"
+		for i, ch := range pb.chapters {
+			line := fmt.Sprintf("%2d. %s  %s", i+1, formatSeconds(ch.Time), ch.Title)
+			if i == m.chapterCursor {
+				s += selectedStyle.Render(line) + "\n"
+			} else {
+				s += line + "\n"
+			}
+		}
+	}
+	if m.status != "" {
+		s += "\n" + m.status + "\n"
+	}
+	return s
+}
+
+func progressBar(position, duration float64, width int) string {
+	filled := 0
+	if duration > 0 {
+		filled = min(int(position/duration*float64(width)), width)
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// rememberedOptions are the mpv properties that, when changed during
+// playback, are remembered for the file and passed to mpv as options the
+// next time it is played.
+var rememberedOptions = []string{"aid", "sid", "video-aspect-override", "sub-delay", "audio-delay"}
+
+// optionValue formats a property value from mpv as an option value, or
+// returns "" for values that cannot be passed back (such as null).
+func optionValue(data json.RawMessage) string {
+	var v any
+	if json.Unmarshal(data, &v) != nil {
+		return ""
+	}
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case float64:
+		// Rounded, as repeated 0.1s delay steps leave float noise behind.
+		return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// trackOption follows a remembered property. The first value reported,
+// and any change before playback gets under way (mpv picking the tracks),
+// is the starting point; later changes are the user's and go into tweaks.
+func (c *mpvClient) trackOption(ev mpvEvent) {
+	if !slices.Contains(rememberedOptions, ev.Name) {
+		return
+	}
+	v := optionValue(ev.Data)
+	if v == "" {
+		return
+	}
+	if old, ok := c.options[ev.Name]; ok && old != v && c.percent > 0 {
+		c.tweaks[ev.Name] = v
+	}
+	c.options[ev.Name] = v
+}
+
+// changedOptions returns the remembered properties changed during playback.
+func (c *mpvClient) changedOptions() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.tweaks)
+}
+
+// optionArgs turns the options remembered for video, and the speed of its
+// show, into mpv arguments.
+func optionArgs(ix *index, st *state, video string) []string {
+	var args []string
+	if speed := st.showSpeed(video); speed > 0 {
+		args = append(args, "--speed="+strconv.FormatFloat(speed, 'f', -1, 64))
+	}
+	e := ix.entry(video)
+	if e == nil {
+		return args
+	}
+	names := make([]string, 0, len(e.Options))
+	for name := range e.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--"+name+"="+e.Options[name])
+	}
+	return args
+}
+
+// speedSteps are the playback speeds "[" and "]" step through.
+var speedSteps = []float64{0.75, 1, 1.25, 1.5, 1.75, 2}
+
+// nextSpeed returns the step after speed in direction dir (1 faster, -1
+// slower), or speed itself at either end.
+func nextSpeed(speed float64, dir int) float64 {
+	if dir > 0 {
+		for _, s := range speedSteps {
+			if s > speed+0.01 {
+				return s
+			}
+		}
+		return speed
+	}
+	for i := len(speedSteps) - 1; i >= 0; i-- {
+		if speedSteps[i] < speed-0.01 {
+			return speedSteps[i]
+		}
+	}
+	return speed
+}
+
+// showSpeed returns the speed remembered for the show video belongs to,
+// or 0 if there is none or remember_speed is off.
+func (st *state) showSpeed(video string) float64 {
+	if !cfg.RememberSpeed {
+		return 0
+	}
+	if ep, ok := parseEpisode(video); ok {
+		return st.Speeds[ep.show]
+	}
+	return 0
+}
+
+// rememberSpeed stores the speed an episode of a show was left playing at
+// for the next episodes, when remember_speed is on. It reports whether the
+// state changed.
+func (st *state) rememberSpeed(video string, c *mpvClient) bool {
+	ep, ok := parseEpisode(video)
+	speed := c.lastSpeed()
+	if !cfg.RememberSpeed || !ok || speed <= 0 {
+		return false
+	}
+	old, remembered := st.Speeds[ep.show]
+	switch {
+	case speed == 1 && remembered:
+		delete(st.Speeds, ep.show)
+	case speed != 1 && speed != old:
+		st.Speeds[ep.show] = speed
+	default:
+		return false
+	}
+	return true
+}
+
+// rememberOptions stores the options changed while video played and
+// reports whether there were any.
+func (ix *index) rememberOptions(video string, changed map[string]string) bool {
+	if len(changed) == 0 {
+		return false
+	}
+	e := ix.ensure(video)
+	if e.Options == nil {
+		e.Options = map[string]string{}
+	}
+	for name, v := range changed {
+		e.Options[name] = v
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// overrideFile is the name of the per-folder settings file.
+const overrideFile = ".movie-launcher.toml"
+
+// folderOverride is a .movie-launcher.toml dropped in a folder. It applies
+// to every video beneath the folder, for rips whose names the launcher
+// cannot make sense of; a file in a deeper folder wins.
+type folderOverride struct {
+	// Title and Year replace the title cleaned from the file names (and
+	// scraped ones).
+	Title string `toml:"title"`
+	Year  int    `toml:"year"`
+	// Show groups the videos as episodes of one show. Those without an
+	// episode marker are numbered within their folder, as season Season
+	// (default 1), in Order: "name" (the default) or "modified".
+	Show   string `toml:"show"`
+	Season int    `toml:"season"`
+	Order  string `toml:"order"`
+	// Player and Args work like a [[player]] rule, ahead of those.
+	Player string   `toml:"player"`
+	Args   []string `toml:"args"`
+}
+
+// merge fills the settings o does not have from a folder further up.
+func (o *folderOverride) merge(other folderOverride) {
+	if o.Title == "" {
+		o.Title, o.Year = other.Title, other.Year
+	}
+	if o.Show == "" {
+		o.Show, o.Season, o.Order = other.Show, other.Season, other.Order
+	}
+	if o.Player == "" && o.Args == nil {
+		o.Player, o.Args = other.Player, other.Args
+	}
+}
+
+var (
+	// folderOverrides caches the override file of each folder, nil when
+	// there is none; folderOrders caches the numbered videos of folders
+	// holding unmarked episodes. Both are cleared when the library is
+	// scanned again.
+	folderOverrides sync.Map
+	folderOrders    sync.Map
+)
+
+func clearOverrides() {
+	folderOverrides.Clear()
+	folderOrders.Clear()
+}
+
+// readOverride returns the override file in dir, or nil.
+func readOverride(dir string) *folderOverride {
+	if o, ok := folderOverrides.Load(dir); ok {
+		return o.(*folderOverride)
+	}
+	var o *folderOverride
+	path := filepath.Join(dir, overrideFile)
+	if _, err := os.Stat(path); err == nil {
+		o = &folderOverride{}
+		if _, err := toml.DecodeFile(path, o); err != nil {
+			logger.Warn("ignoring folder settings", "path", path, "err", err)
+			o = nil
+		}
+	}
+	folderOverrides.Store(dir, o)
+	return o
+}
+
+// overrideFor merges the override files from video's folder up to its
+// library root.
+func overrideFor(video string) folderOverride {
+	var o folderOverride
+	root := rootOf(video)
+	if root == "" {
+		return o
+	}
+	for dir := filepath.Dir(video); ; dir = filepath.Dir(dir) {
+		if f := readOverride(dir); f != nil {
+			o.merge(*f)
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return o
+		}
+	}
+}
+
+// folderPosition returns the 1-based position of video among the videos in
+// its folder, sorted by order, or 0 if it cannot be found.
+func folderPosition(video, order string) int {
+	dir := filepath.Dir(video)
+	key := dir + "\x00" + order
+	cached, ok := folderOrders.Load(key)
+	if !ok {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return 0
+		}
+		type file struct {
+			path    string
+			modTime int64
+		}
+		var files []file
+		for _, entry := range entries {
+			if entry.IsDir() || !isVideoFile(entry.Name()) {
+				continue
+			}
+			f := file{path: filepath.Join(dir, entry.Name())}
+			if info, err := entry.Info(); err == nil {
+				f.modTime = info.ModTime().UnixNano()
+			}
+			files = append(files, f)
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			if order == "modified" && files[i].modTime != files[j].modTime {
+				return files[i].modTime < files[j].modTime
+			}
+			return files[i].path < files[j].path
+		})
+		positions := make(map[string]int, len(files))
+		for i, f := range files {
+			positions[f.path] = i + 1
+		}
+		cached, _ = folderOrders.LoadOrStore(key, positions)
+	}
+	return cached.(map[string]int)[video]
+}
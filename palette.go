@@ -0,0 +1,213 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// paletteCommand is a command of the command palette, opened with ":". It
+// runs command of the list, shown with its key, or calls run for commands
+// without a key of their own.
+type paletteCommand struct {
+	name    string
+	command string
+	run     func(m *model) tea.Cmd
+}
+
+// paletteKeys are the commands of the list that keys run.
+var paletteKeys = []paletteCommand{
+	{name: "Filter the list", command: "filter"},
+	{name: "Tag the selected video", command: "tag"},
+	{name: "Rate the selected video", command: "rate"},
+	{name: "Pin or unpin the selected video", command: "pin"},
+	{name: "Hide or unhide the selected video", command: "hide"},
+	{name: "Hide or unhide the selected video's folder", command: "hide-folder"},
+	{name: "Show or hide hidden videos", command: "show-hidden"},
+	{name: "Next sort order", command: "sort"},
+	{name: "Next second sort order", command: "then-sort"},
+	{name: "Next way of naming videos", command: "names"},
+	{name: "Toggle the column view", command: "columns"},
+	{name: "Browse by folder", command: "folders"},
+	{name: "Pick a library root", command: "roots"},
+	{name: "Jump to a letter", command: "jump"},
+	{name: "Play the next episode", command: "next-episode"},
+	{name: "Listen to the selected video", command: "listen"},
+	{name: "Watch with Syncplay", command: "syncplay"},
+	{name: "Play the trailer", command: "trailer"},
+	{name: "Thumbnails of the selected video", command: "thumbnails"},
+	{name: "Run an action on the selected video", command: "actions"},
+	{name: "Now playing", command: "now-playing"},
+	{name: "Toggle loudness normalization", command: "loudnorm"},
+	{name: "Move the player to the next screen", command: "screen"},
+	{name: "Rescan the selected video's folder", command: "rescan"},
+	{name: "Root health", command: "health"},
+	{name: "Checksums", command: "checksums"},
+	{name: "Quit", command: "quit"},
+}
+
+// paletteCommands lists every command of the palette: those of the keys,
+// then choosing a sort order or naming directly, the bulk commands,
+// clearing the filter, showing a tag and limiting the list to a root.
+func (m model) paletteCommands() []paletteCommand {
+	commands := slices.Clone(paletteKeys)
+	for i, mode := range sortModes {
+		commands = append(commands, paletteCommand{name: "Sort by " + mode, run: func(m *model) tea.Cmd {
+			m.sortMode = i
+			m.refresh()
+			m.status = m.sortStatus()
+			return nil
+		}})
+	}
+	for i, mode := range sortModes {
+		commands = append(commands, paletteCommand{name: "Then sort by " + mode, run: func(m *model) tea.Cmd {
+			m.thenMode = i
+			m.refresh()
+			m.status = m.sortStatus()
+			return nil
+		}})
+	}
+	for i, mode := range displayModes {
+		commands = append(commands, paletteCommand{name: "Show " + mode + " names", run: func(m *model) tea.Cmd {
+			m.displayMode = i
+			m.labels = make(map[string]string, len(m.allVideos))
+			m.refresh()
+			m.status = "Showing " + mode + " names"
+			return nil
+		}})
+	}
+	commands = append(commands, m.bulkCommands()...)
+	if m.filter != "" {
+		commands = append(commands, paletteCommand{name: "Clear the filter", run: func(m *model) tea.Cmd {
+			m.useFilter("")
+			return nil
+		}})
+	}
+	for _, tag := range m.index.allTags() {
+		commands = append(commands, paletteCommand{name: "Show tag " + tag, run: func(m *model) tea.Cmd {
+			m.useFilter("tag:" + tag)
+			return nil
+		}})
+	}
+	if len(videoDirs) > 1 {
+		commands = append(commands, paletteCommand{name: "Show all roots", run: func(m *model) tea.Cmd {
+			m.root = ""
+			m.status = "Showing all roots"
+			m.refresh()
+			return nil
+		}})
+		for _, root := range videoDirs {
+			commands = append(commands, paletteCommand{name: "Show root " + root, run: func(m *model) tea.Cmd {
+				m.root = root
+				m.status = "Showing " + root
+				m.refresh()
+				return nil
+			}})
+		}
+	}
+	return commands
+}
+
+// useFilter filters the list with filter, as if typed after "/".
+func (m *model) useFilter(filter string) {
+	m.filter = filter
+	m.searchInput.SetValue(filter)
+	m.refresh()
+}
+
+// paletteMatches are the commands matching what was typed in the palette,
+// the best matches first.
+func (m model) paletteMatches() []paletteCommand {
+	query := strings.ReplaceAll(m.paletteInput.Value(), " ", "")
+	type match struct {
+		command paletteCommand
+		score   int
+	}
+	var matches []match
+	for _, c := range m.paletteCommands() {
+		if score, ok := fuzzyScore(query, c.name); ok {
+			matches = append(matches, match{c, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+	commands := make([]paletteCommand, len(matches))
+	for i, match := range matches {
+		commands[i] = match.command
+	}
+	return commands
+}
+
+// openPalette shows the command palette.
+func (m *model) openPalette() tea.Cmd {
+	m.paletteMode = true
+	m.paletteCursor = 0
+	m.paletteInput.SetValue("")
+	m.paletteInput.Focus()
+	return textinput.Blink
+}
+
+func (m model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	matches := m.paletteMatches()
+	switch keys().command("palette", msg.String()) {
+	case "cancel":
+		m.paletteMode = false
+		m.paletteInput.Blur()
+	case "up":
+		m.paletteCursor = max(m.paletteCursor-1, 0)
+	case "down":
+		m.paletteCursor = max(min(m.paletteCursor+1, len(matches)-1), 0)
+	case "run":
+		m.paletteMode = false
+		m.paletteInput.Blur()
+		if m.paletteCursor >= len(matches) {
+			break
+		}
+		c := matches[m.paletteCursor]
+		if c.command != "" {
+			return m.listCommand(c.command, 1, false)
+		}
+		cmd := c.run(&m)
+		return m, cmd
+	default:
+		var cmd tea.Cmd
+		m.paletteInput, cmd = m.paletteInput.Update(msg)
+		m.paletteCursor = 0
+		return m, cmd
+	}
+	return m, nil
+}
+
+// paletteRows is the number of commands the palette has room for.
+func (m model) paletteRows() int {
+	if m.height == 0 {
+		return 20
+	}
+	return max(m.height-3, 1)
+}
+
+func (m model) viewPalette() string {
+	var b strings.Builder
+	b.WriteString(m.fit(keys().help("Commands - type to search, %s/%s to pick, %s to run, %s to cancel", "palette", "up", "down", "run", "cancel")) + "\n")
+	b.WriteString(":" + m.paletteInput.View() + "\n\n")
+	matches := m.paletteMatches()
+	if len(matches) == 0 {
+		b.WriteString(hiddenStyle.Render(tr("no matching command")))
+		return b.String()
+	}
+	rows := m.paletteRows()
+	top := min(max(m.paletteCursor-rows/2, 0), max(len(matches)-rows, 0))
+	for i := top; i < min(top+rows, len(matches)); i++ {
+		line := matches[i].name
+		if key := keys().key("list", matches[i].command); key != "" {
+			line += "  " + hiddenStyle.Render(key)
+		}
+		line = m.fit(line)
+		if i == m.paletteCursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
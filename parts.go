@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// partPattern matches the marker of one part of a film split across files,
+// such as "CD1", "cd.2" or "part1", capturing its number. It is bounded by
+// anything but a letter or digit rather than \b, which takes "_" for a
+// letter and so misses "Movie_cd1".
+var partPattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:cd|part|pt)[ ._-]?(\d{1,2})(?:[^a-z0-9]|$)`)
+
+// partOf returns what names video apart from its part marker, the same for
+// every part of a film, and the number of the part, which is 0 when video
+// has no marker.
+func partOf(video string) (string, int) {
+	name := filepath.Base(video)
+	locs := partPattern.FindAllStringSubmatchIndex(name, -1)
+	if len(locs) == 0 {
+		return "", 0
+	}
+	loc := locs[len(locs)-1]
+	n, _ := strconv.Atoi(name[loc[2]:loc[3]])
+	return filepath.Join(filepath.Dir(video), strings.ToLower(name[:loc[0]]+"*"+name[loc[1]:])), n
+}
+
+func isPart(video string) bool {
+	_, n := partOf(video)
+	return n > 0
+}
+
+// multiPart finds the films among videos split into parts numbered 1, 2
+// and so on, in one folder and named alike, and returns their parts in
+// order by the first.
+func multiPart(videos []string) map[string][]string {
+	films := map[string][]string{}
+	for _, video := range videos {
+		if key, n := partOf(video); n > 0 {
+			films[key] = append(films[key], video)
+		}
+	}
+	split := map[string][]string{}
+	for _, parts := range films {
+		if len(parts) < 2 {
+			continue
+		}
+		slices.SortFunc(parts, func(a, b string) int {
+			_, na := partOf(a)
+			_, nb := partOf(b)
+			return na - nb
+		})
+		numbered := true
+		for i, part := range parts {
+			if _, n := partOf(part); n != i+1 {
+				numbered = false
+			}
+		}
+		if numbered {
+			split[parts[0]] = parts
+		}
+	}
+	return split
+}
+
+// listOnce lists the videos of each group of groups, as returned by
+// multiPart or editionsOf, once among videos, by the first of the group
+// where any of them is.
+func listOnce(videos []string, groups map[string][]string) []string {
+	if len(groups) == 0 {
+		return videos
+	}
+	first := map[string]string{}
+	for video, group := range groups {
+		for _, other := range group {
+			first[other] = video
+		}
+	}
+	listed := map[string]bool{}
+	kept := make([]string, 0, len(videos))
+	for _, video := range videos {
+		if f, ok := first[video]; ok {
+			video = f
+		}
+		if !listed[video] {
+			listed[video] = true
+			kept = append(kept, video)
+		}
+	}
+	return kept
+}
+
+// videoParts returns the parts of the film video is the first part of,
+// found next to it, or nil when it is not split.
+func videoParts(video string) []string {
+	if !isPart(video) {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Dir(video))
+	if err != nil {
+		return nil
+	}
+	var videos []string
+	for _, e := range entries {
+		if !e.IsDir() && isVideoFile(e.Name()) {
+			videos = append(videos, filepath.Join(filepath.Dir(video), e.Name()))
+		}
+	}
+	return multiPart(videos)[video]
+}
+
+// partsOf returns the parts of the film video is the first part of, or
+// just video when it is not split.
+func partsOf(video string) []string {
+	if parts := videoParts(video); parts != nil {
+		return parts
+	}
+	return []string{video}
+}
+
+// partsPlaylist writes an M3U playlist of the parts of a split film, whose
+// first part is video, to the cache, for the player to play them one after
+// the other, and returns its path.
+func partsPlaylist(video string, parts []string) (string, error) {
+	path := filepath.Join(cacheDir(), "parts", watchLaterName(video)+".m3u")
+	return path, replaceFile(path, []byte("#EXTM3U\n"+strings.Join(parts, "\n")+"\n"))
+}
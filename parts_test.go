@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestPartOf(t *testing.T) {
+	tests := []struct {
+		video string
+		n     int
+	}{
+		{"/films/Heat.1995.cd1.avi", 1},
+		{"/films/Heat.1995.CD.2.avi", 2},
+		{"/films/Movie_cd1.avi", 1},
+		{"/films/Movie Part 1.mkv", 1},
+		{"/films/Movie.pt2.mkv", 2},
+		{"/films/Apocalypse Now.mkv", 0},
+		{"/films/Departures.mkv", 0},
+	}
+	for _, tt := range tests {
+		if _, n := partOf(tt.video); n != tt.n {
+			t.Errorf("partOf(%q) = %d, want %d", tt.video, n, tt.n)
+		}
+	}
+}
+
+func TestMultiPart(t *testing.T) {
+	tests := []struct {
+		name   string
+		videos []string
+		want   int
+	}{
+		{"cd", []string{"/f/Heat.cd2.avi", "/f/Heat.cd1.avi"}, 2},
+		{"underscore", []string{"/f/Movie_cd1.avi", "/f/Movie_cd2.avi"}, 2},
+		{"part", []string{"/f/Movie Part 1.mkv", "/f/Movie Part 2.mkv"}, 2},
+		{"different years", []string{"/f/Deathly Hallows Part 1 (2010).mkv", "/f/Deathly Hallows Part 2 (2011).mkv"}, 0},
+		{"different folders", []string{"/a/Heat.cd1.avi", "/b/Heat.cd2.avi"}, 0},
+		{"gap", []string{"/f/Heat.cd1.avi", "/f/Heat.cd3.avi"}, 0},
+	}
+	for _, tt := range tests {
+		split := multiPart(tt.videos)
+		got := 0
+		for _, parts := range split {
+			got = len(parts)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %d parts (%v), want %d", tt.name, got, split, tt.want)
+		}
+		for first, parts := range split {
+			if _, n := partOf(first); n != 1 || parts[0] != first {
+				t.Errorf("%s: %q leads %v", tt.name, first, parts)
+			}
+		}
+	}
+}
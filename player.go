@@ -0,0 +1,685 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// observedProperties are the mpv properties mirrored in the now-playing bar.
+var observedProperties = []string{"media-title", "time-pos", "duration", "pause", "chapter", "chapter-list", "track-list", "percent-pos", "playlist-pos", "playlist-count", "loop-file", "ab-loop-a", "ab-loop-b", "speed"}
+
+var nowPlayingStyle = lipgloss.NewStyle().Reverse(true).Bold(true)
+
+// loudnormFilter is the labelled mpv audio filter that evens out loud and
+// quiet passages, so it can be toggled during playback.
+const loudnormFilter = "@loudnorm:lavfi=[loudnorm]"
+
+// loudnormToggled flips the loudnorm config setting for this session.
+var loudnormToggled bool
+
+// loudnormEnabled reports whether mpv is started with loudnormFilter.
+func loudnormEnabled() bool {
+	return cfg.Loudnorm != loudnormToggled
+}
+
+// playerName is the name of the player's executable, such as "mpv".
+func playerName(player string) string {
+	return strings.TrimSuffix(filepath.Base(player), ".exe")
+}
+
+// isMpv reports whether player is mpv, which supports the extra integration
+// flags below.
+func isMpv(player string) bool {
+	return playerName(player) == "mpv"
+}
+
+// watchLaterDir is where mpv keeps resume positions for the active profile.
+func watchLaterDir() string {
+	return filepath.Join(profileDir(), "watch_later")
+}
+
+// hasResume reports whether mpv saved a resume position for video, or any
+// part of a film split across files, which means it was quit before
+// reaching the end.
+func hasResume(video string) bool {
+	return slices.ContainsFunc(partsOf(video), func(part string) bool {
+		_, err := os.Stat(filepath.Join(watchLaterDir(), watchLaterName(part)))
+		return err == nil
+	})
+}
+
+// watchLaterName is the name mpv gives the resume file of video: the MD5 of
+// its absolute path.
+func watchLaterName(video string) string {
+	if abs, err := filepath.Abs(video); err == nil {
+		video = abs
+	}
+	return fmt.Sprintf("%X", md5.Sum([]byte(video)))
+}
+
+// playerCommand returns the player for video and its arguments: ssh when
+// playing on a remote target, otherwise those of the first [[player]] rule
+// matching video, or VIDEO_PLAYER. The player is given a playlist of the
+// parts of a film split across files (see videoParts).
+func playerCommand(video string) (string, []string) {
+	if remoteTarget != nil {
+		return remoteTarget.command(video)
+	}
+	player := videoPlayer
+	var extra []string
+	if o := overrideFor(video); o.Player != "" || o.Args != nil {
+		if o.Player != "" {
+			player = o.Player
+		}
+		extra = o.Args
+	} else {
+		for _, rule := range cfg.Players {
+			if !rule.matches(video) {
+				continue
+			}
+			if rule.Command != "" {
+				player = rule.Command
+			}
+			extra = rule.Args
+			break
+		}
+	}
+	args := windowArgs(player)
+	if isMpv(player) && isImageFile(video) {
+		return player, append(append(args, extra...), slideshowArgs(video)...)
+	}
+	if isMpv(player) {
+		args = append(args, "--save-position-on-quit", "--watch-later-directory="+watchLaterDir())
+		if loudnormEnabled() {
+			args = append(args, "--af-append="+loudnormFilter)
+		}
+	}
+	args = append(args, extra...)
+	if parts := videoParts(video); parts != nil {
+		playlist, err := partsPlaylist(video, parts)
+		if err == nil {
+			return player, append(args, playlist)
+		}
+		logger.Warn("writing the playlist of parts failed", "video", video, "err", err)
+	}
+	return player, append(args, video)
+}
+
+// windowArgs asks the player for the window set by window (or --window)
+// and the screen from playerScreen, in the arguments of mpv, VLC or
+// MPlayer.
+func windowArgs(player string) []string {
+	var fullscreen, windowed []string
+	switch playerName(player) {
+	case "mpv":
+		fullscreen, windowed = []string{"--fs"}, []string{"--no-fs"}
+	case "vlc", "cvlc":
+		fullscreen, windowed = []string{"--fullscreen"}, []string{"--no-fullscreen"}
+	case "mplayer":
+		fullscreen, windowed = []string{"-fs"}, []string{"-nofs"}
+	}
+	var args []string
+	switch cfg.Window {
+	case "fullscreen":
+		args = append(args, fullscreen...)
+	case "windowed":
+		args = append(args, windowed...)
+	}
+	if screen := playerScreen(); screen != nil {
+		args = append(args, screenArgs(player, *screen)...)
+	}
+	return args
+}
+
+// listenCommand is playerCommand with the video track switched off, for
+// podcasts, concerts or listening while working in the terminal.
+func listenCommand(video string) (string, []string) {
+	player, args := playerCommand(video)
+	switch playerName(player) {
+	case "mpv", "vlc", "cvlc":
+		args = append([]string{"--no-video"}, args...)
+	case "mplayer":
+		args = append([]string{"-novideo"}, args...)
+	}
+	return player, args
+}
+
+// slideshowArgs makes mpv show the photos in image's folder one after the
+// other, starting with image.
+func slideshowArgs(image string) []string {
+	seconds := 5.0
+	if cfg.ImageDuration.Duration > 0 {
+		seconds = cfg.ImageDuration.Seconds()
+	}
+	args := []string{fmt.Sprintf("--image-display-duration=%g", seconds)}
+	entries, err := os.ReadDir(filepath.Dir(image))
+	if err != nil {
+		return append(args, image)
+	}
+	var photos []string
+	start := 0
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(image), entry.Name())
+		if !entry.IsDir() && isImageFile(path) {
+			if path == image {
+				start = len(photos)
+			}
+			photos = append(photos, path)
+		}
+	}
+	if len(photos) == 0 {
+		return append(args, image)
+	}
+	args = append(args, fmt.Sprintf("--playlist-start=%d", start))
+	return append(args, photos...)
+}
+
+// recordStart adds video to the profile's watch history and fingerprints
+// it, so the history follows the file if it is moved.
+func recordStart(video string, ix *index, st *state) error {
+	st.addHistory(video)
+	ix.track(video)
+	if err := ix.save(); err != nil {
+		return err
+	}
+	return st.save()
+}
+
+// playbackEnd says how playback ended.
+type playbackEnd int
+
+const (
+	// endFinished means the video was watched to the end, or at least
+	// watched_percent of it.
+	endFinished playbackEnd = iota
+	// endStopped means the player was quit part way through.
+	endStopped
+	// endCrashed means the player failed or was killed.
+	endCrashed
+)
+
+func (e playbackEnd) String() string {
+	switch e {
+	case endFinished:
+		return "finished"
+	case endStopped:
+		return "stopped"
+	}
+	return "crashed"
+}
+
+// defaultWatchedPercent is how much of a video must be played for it to
+// count as watched, so quitting during the end credits still does.
+const defaultWatchedPercent = 90
+
+func watchedPercent() float64 {
+	if cfg.WatchedPercent > 0 {
+		return cfg.WatchedPercent
+	}
+	return defaultWatchedPercent
+}
+
+// endOf works out how playback of video ended from the player's exit
+// status and, when mpv was followed over IPC (client is not nil), where
+// it stopped and why. Only the last entry of a playlist, such as that of
+// the parts of a split film, counts towards finishing. Without IPC, mpv
+// leaving a resume position behind means it was quit early; other players
+// are taken at their word when they exit cleanly.
+func endOf(video string, exitErr error, client *mpvClient) playbackEnd {
+	percent, reason, last := -1.0, "", true
+	if client != nil {
+		percent, reason = client.ended()
+		last = client.lastEntry()
+	}
+	switch {
+	case reason == "error":
+		return endCrashed
+	case last && (reason == "eof" || percent >= watchedPercent()):
+		return endFinished
+	case exitErr != nil:
+		return endCrashed
+	case percent >= 0 || hasResume(video):
+		return endStopped
+	}
+	return endFinished
+}
+
+// followPlayback connects to the IPC socket of an mpv running in the
+// foreground to learn how playback ends (see endOf) and, when skip is not
+// nil, to skip intros and credits. It returns nil when mpv cannot be
+// reached.
+func followPlayback(socket string, skip *skipper) *mpvClient {
+	client, err := dialMpv(socket, 5*time.Second)
+	if err != nil {
+		logger.Warn("mpv IPC unavailable", "socket", socket, "err", err)
+		return nil
+	}
+	go func() {
+		defer catchPanic()
+		for ev := range client.events {
+			if skip == nil {
+				continue
+			}
+			if args := skip.handle(ev); args != nil {
+				// Not waiting for the answer keeps the events flowing.
+				go client.command(args...)
+			}
+		}
+	}()
+	properties := slices.Concat([]string{"percent-pos", "playlist-pos", "playlist-count", "speed"}, rememberedOptions)
+	if skip != nil {
+		properties = append(properties, skipProperties...)
+	}
+	for i, name := range properties {
+		if err := client.observe(i+1, name); err != nil {
+			logger.Warn("observing mpv failed", "property", name, "err", err)
+		}
+	}
+	return client
+}
+
+// recordFinish marks video as watched when playback finished, and forgets
+// mpv's resume position, which would start the next viewing in the credits.
+func recordFinish(video string, st *state, end playbackEnd) error {
+	logger.Info("playback ended", "video", video, "end", end)
+	if end != endFinished {
+		return nil
+	}
+	markWatched(video, st)
+	return st.save()
+}
+
+// markWatched records that video, and every part of a film split across
+// files, was watched now, without saving.
+func markWatched(video string, st *state) {
+	now := time.Now()
+	for _, part := range partsOf(video) {
+		os.Remove(filepath.Join(watchLaterDir(), watchLaterName(part)))
+		st.Watched[part] = now
+	}
+}
+
+// play runs the player (as returned by command, usually playerCommand) in
+// the foreground, surrounded by the pre_play and post_play hooks, and
+// records the video in the profile's watch history. It reports how
+// playback ended.
+func play(video string, ix *index, st *state, command func(string) (string, []string)) (playbackEnd, error) {
+	if err := recordStart(video, ix, st); err != nil {
+		return endCrashed, err
+	}
+	env := hookEnv(video, ix, st)
+	if err := runHook("pre_play", cfg.PrePlay, env, os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	player, args := command(video)
+	socket := ""
+	if isMpv(player) {
+		socket = ipcSocket()
+		os.Remove(socket)
+		args = slices.Concat([]string{"--input-ipc-server=" + socket}, optionArgs(ix, st, video), args)
+	}
+	cmd := exec.Command(player, args...)
+	stdin := terminalInput()
+	if stdin != os.Stdin {
+		defer stdin.Close()
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = stdin
+	logger.Info("starting player", "cmd", cmd.Args, "detached", false)
+	if err := cmd.Start(); err != nil {
+		return endCrashed, err
+	}
+	var client *mpvClient
+	if socket != "" {
+		client = followPlayback(socket, newSkipper(video))
+	}
+	err := cmd.Wait()
+	end := endOf(video, err, client)
+	if client != nil {
+		client.close()
+		if ix.rememberOptions(video, client.changedOptions()) {
+			if err := ix.save(); err != nil {
+				logger.Warn("saving player options failed", "err", err)
+			}
+		}
+		if st.rememberSpeed(video, client) {
+			if err := st.save(); err != nil {
+				logger.Warn("saving speed failed", "err", err)
+			}
+		}
+	}
+	logger.Info("player exited", "video", video, "err", err)
+	if err := runHook("post_play", cfg.PostPlay, finishedEnv(env, end), os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	if err := recordFinish(video, st, end); err != nil {
+		return end, err
+	}
+	return end, err
+}
+
+// playAnnounced plays video in the foreground like play, with desktop
+// notifications and MQTT events around it. videos are the other videos
+// listed, among which the next episode is looked for.
+func playAnnounced(video string, videos []string, ix *index, st *state, command func(string) (string, []string)) error {
+	notifyStarted(video)
+	if err := publishEvent("play", video, ix); err != nil {
+		logger.Warn("publishing event failed", "event", "play", "err", err)
+	}
+	end, err := play(video, ix, st, command)
+	if err := publishEvent("stop", video, ix); err != nil {
+		logger.Warn("publishing event failed", "event", "stop", "err", err)
+	}
+	notifyFinished(video, videos, st, ix, end)
+	return err
+}
+
+// terminalInput returns stdin, or the terminal itself when stdin is not one
+// (as with --stdin), so the player still gets keyboard input.
+func terminalInput() *os.File {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return os.Stdin
+	}
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		return tty
+	}
+	return os.Stdin
+}
+
+// playback is a player running next to the TUI in detached mode. When the
+// player is mpv, its state is followed over the IPC socket.
+type playback struct {
+	video    string
+	env      []string
+	cmd      *exec.Cmd
+	client   *mpvClient
+	title    string
+	position float64
+	duration float64
+	paused   bool
+	chapter  int
+	chapters []chapter
+	tracks   []track
+	// subDelay and audioDelay are mpv's sub-delay and audio-delay, in
+	// seconds.
+	subDelay   float64
+	audioDelay float64
+	// loopFile repeats the video; loopA and loopB are the A-B loop points,
+	// -1 when unset.
+	loopFile     bool
+	loopA, loopB float64
+	speed        float64
+	// skip seeks past intros and credits, if a [[skip]] rule matches.
+	skip *skipper
+}
+
+type chapter struct {
+	Title string  `json:"title"`
+	Time  float64 `json:"time"`
+}
+
+// track is an entry of mpv's track-list property.
+type track struct {
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Lang     string `json:"lang"`
+	Codec    string `json:"codec"`
+	Selected bool   `json:"selected"`
+}
+
+func (t track) String() string {
+	var parts []string
+	for _, s := range []string{t.Lang, t.Title, t.Codec} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return fmt.Sprintf("#%d %s", t.ID, strings.Join(parts, " "))
+}
+
+// selectedTrack describes the active track of the given type ("audio" or
+// "sub"), or "off" when none is selected.
+func (pb *playback) selectedTrack(kind string) string {
+	for _, t := range pb.tracks {
+		if t.Type == kind && t.Selected {
+			return t.String()
+		}
+	}
+	return "off"
+}
+
+type playbackStartedMsg struct {
+	pb      *playback
+	err     error
+	hookErr error
+}
+
+type playbackEventMsg struct {
+	pb    *playback
+	event mpvEvent
+}
+
+// playbackErrorMsg reports a failed IPC command.
+type playbackErrorMsg struct{ err error }
+
+type playbackExitedMsg struct {
+	pb      *playback
+	err     error
+	end     playbackEnd
+	hookErr error
+}
+
+func ipcSocket() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("movie-launcher-%d.sock", os.Getpid()))
+}
+
+// startDetached runs the pre_play hook and launches the player (as
+// returned by command) in the background. env describes the video to the
+// hooks and options are the mpv options remembered for it (see optionArgs).
+func startDetached(video string, env, options []string, command func(string) (string, []string)) tea.Cmd {
+	return func() tea.Msg {
+		hookErr := runHook("pre_play", cfg.PrePlay, env, io.Discard)
+		player, args := command(video)
+		socket := ""
+		if isMpv(player) {
+			socket = ipcSocket()
+			os.Remove(socket)
+			args = slices.Concat([]string{"--input-ipc-server=" + socket, "--no-terminal"}, options, args)
+		}
+		cmd := exec.Command(player, args...)
+		logger.Info("starting player", "cmd", cmd.Args, "detached", true)
+		if err := cmd.Start(); err != nil {
+			logger.Error("player failed to start", "err", err)
+			return playbackStartedMsg{err: err}
+		}
+		notifyStarted(video)
+
+		pb := &playback{video: video, env: env, cmd: cmd, title: filepath.Base(video), skip: newSkipper(video), loopA: -1, loopB: -1}
+		if socket != "" {
+			if client, err := dialMpv(socket, 5*time.Second); err == nil {
+				pb.client = client
+				for i, name := range slices.Concat(observedProperties, rememberedOptions) {
+					client.observe(i+1, name)
+				}
+			} else {
+				logger.Warn("mpv IPC unavailable", "socket", socket, "err", err)
+			}
+		}
+		return playbackStartedMsg{pb: pb, hookErr: hookErr}
+	}
+}
+
+func (pb *playback) waitForExit() tea.Cmd {
+	return func() tea.Msg {
+		err := pb.cmd.Wait()
+		end := endOf(pb.video, err, pb.client)
+		logger.Info("player exited", "video", pb.video, "err", err)
+		if pb.client != nil {
+			pb.client.close()
+		}
+		hookErr := runHook("post_play", cfg.PostPlay, finishedEnv(pb.env, end), io.Discard)
+		return playbackExitedMsg{pb: pb, err: err, end: end, hookErr: hookErr}
+	}
+}
+
+func (pb *playback) waitForEvent() tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-pb.client.events
+		if !ok {
+			return nil
+		}
+		return playbackEventMsg{pb: pb, event: ev}
+	}
+}
+
+// stop asks the player to quit, letting mpv save the resume position.
+func (pb *playback) stop() tea.Cmd {
+	return func() tea.Msg {
+		if pb.client != nil {
+			if _, err := pb.client.command("quit"); err == nil {
+				return nil
+			}
+		}
+		pb.cmd.Process.Kill()
+		return nil
+	}
+}
+
+func (pb *playback) apply(ev mpvEvent) {
+	if ev.Event != "property-change" {
+		return
+	}
+	switch ev.Name {
+	case "media-title":
+		var title string
+		if json.Unmarshal(ev.Data, &title) == nil && title != "" {
+			pb.title = title
+		}
+	case "time-pos":
+		json.Unmarshal(ev.Data, &pb.position)
+	case "duration":
+		json.Unmarshal(ev.Data, &pb.duration)
+	case "pause":
+		json.Unmarshal(ev.Data, &pb.paused)
+	case "chapter":
+		json.Unmarshal(ev.Data, &pb.chapter)
+	case "chapter-list":
+		pb.chapters = nil
+		json.Unmarshal(ev.Data, &pb.chapters)
+	case "track-list":
+		pb.tracks = nil
+		json.Unmarshal(ev.Data, &pb.tracks)
+	case "sub-delay":
+		json.Unmarshal(ev.Data, &pb.subDelay)
+	case "audio-delay":
+		json.Unmarshal(ev.Data, &pb.audioDelay)
+	case "loop-file":
+		// "inf" or a count when looping, false (or "no") when not.
+		v := optionValue(ev.Data)
+		pb.loopFile = v != "" && v != "no" && v != "0"
+	case "ab-loop-a":
+		pb.loopA = loopPoint(ev.Data)
+	case "ab-loop-b":
+		pb.loopB = loopPoint(ev.Data)
+	case "speed":
+		json.Unmarshal(ev.Data, &pb.speed)
+	}
+}
+
+// currentSpeed is the playback speed, 1 until mpv reports it.
+func (pb *playback) currentSpeed() float64 {
+	if pb.speed <= 0 {
+		return 1
+	}
+	return pb.speed
+}
+
+// loopPoint reads an A-B loop point, which mpv reports as "no" when unset.
+func loopPoint(data json.RawMessage) float64 {
+	var t float64
+	if json.Unmarshal(data, &t) != nil {
+		return -1
+	}
+	return t
+}
+
+// screenshotMsg reports where mpv saved a screenshot; path is empty for
+// mpv versions that do not say.
+type screenshotMsg struct {
+	path string
+	err  error
+}
+
+// screenshot has mpv save the current frame, with subtitles.
+func (pb *playback) screenshot() tea.Cmd {
+	return func() tea.Msg {
+		if pb.client == nil {
+			return nil
+		}
+		data, err := pb.client.command("screenshot")
+		if err != nil {
+			return screenshotMsg{err: err}
+		}
+		var resp struct {
+			Filename string `json:"filename"`
+		}
+		json.Unmarshal(data, &resp)
+		return screenshotMsg{path: resp.Filename}
+	}
+}
+
+// send runs an mpv command in the background.
+func (pb *playback) send(args ...any) tea.Cmd {
+	return func() tea.Msg {
+		if pb.client == nil {
+			return nil
+		}
+		if _, err := pb.client.command(args...); err != nil {
+			return playbackErrorMsg{err}
+		}
+		return nil
+	}
+}
+
+func (pb *playback) view(width int) string {
+	icon := "▶"
+	if pb.paused {
+		icon = "⏸"
+	}
+	bar := fmt.Sprintf(" %s %s ", icon, pb.title)
+	if pb.client != nil {
+		bar += fmt.Sprintf(" %s / %s ", formatSeconds(pb.position), formatSeconds(pb.duration))
+	}
+	if speed := pb.currentSpeed(); speed != 1 {
+		bar += fmt.Sprintf(" %g× ", speed)
+	}
+	if pb.paused {
+		bar += " (paused) "
+	}
+	if width > 0 {
+		bar = truncateMiddle(bar, width)
+	}
+	return nowPlayingStyle.Width(width).Render(bar)
+}
+
+func formatSeconds(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
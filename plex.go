@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// plexConfig is the [plex] table. The token is the X-Plex-Token of the
+// account whose watch state is imported.
+type plexConfig struct {
+	URL   string `toml:"url"`
+	Token string `toml:"token"`
+}
+
+// plexItem is a film or episode in a Plex library listing.
+type plexItem struct {
+	Title        string `json:"title"`
+	Year         int    `json:"year"`
+	Show         string `json:"grandparentTitle"`
+	Season       int    `json:"parentIndex"`
+	Episode      int    `json:"index"`
+	ViewCount    int    `json:"viewCount"`
+	ViewOffset   int64  `json:"viewOffset"` // milliseconds
+	LastViewedAt int64  `json:"lastViewedAt"`
+}
+
+func (c plexConfig) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(c.URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Plex-Token", c.Token)
+	return fetchJSON(req, out)
+}
+
+// plexRecords lists the watched and partly watched films and episodes in
+// every movie and TV library of the Plex server.
+func plexRecords(ctx context.Context, c plexConfig) ([]watchRecord, error) {
+	if c.URL == "" || c.Token == "" {
+		return nil, fmt.Errorf("set url and token under [plex] in config.toml")
+	}
+	var sections struct {
+		MediaContainer struct {
+			Directory []struct {
+				Key  string `json:"key"`
+				Type string `json:"type"`
+			} `json:"Directory"`
+		} `json:"MediaContainer"`
+	}
+	if err := c.get(ctx, "/library/sections", &sections); err != nil {
+		return nil, err
+	}
+	var records []watchRecord
+	for _, dir := range sections.MediaContainer.Directory {
+		path := "/library/sections/" + dir.Key + "/all"
+		switch dir.Type {
+		case "movie":
+		case "show":
+			// Type 4 lists the episodes rather than the shows.
+			path += "?type=4"
+		default:
+			continue
+		}
+		var items struct {
+			MediaContainer struct {
+				Metadata []plexItem `json:"Metadata"`
+			} `json:"MediaContainer"`
+		}
+		if err := c.get(ctx, path, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items.MediaContainer.Metadata {
+			if item.ViewCount == 0 && item.ViewOffset == 0 {
+				continue
+			}
+			r := watchRecord{title: item.Title, year: item.Year}
+			if dir.Type == "show" {
+				r.show, r.season, r.episode = item.Show, item.Season, item.Episode
+			}
+			if item.ViewCount > 0 {
+				r.watched = time.Unix(item.LastViewedAt, 0)
+			} else {
+				r.position = float64(item.ViewOffset) / 1000
+			}
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
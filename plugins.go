@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// pluginTimeout bounds every call into a plugin.
+const pluginTimeout = 30 * time.Second
+
+// plugin is an executable in the plugins directory. The launcher runs it with
+// a command name as its only argument, writes a JSON request to its stdin and
+// reads a JSON response from its stdout. Commands:
+//
+//	describe: {} -> {"name", "scraper", "source", "actions": [{"name", "description"}]}
+//	scrape:   {"path"} -> metadata ({"title", "year", "runtime", "plot", "genres", "actors"})
+//	list:     {} -> {"videos": [paths or URLs]}
+//	action:   {"action", "path"} -> {"message"}
+type plugin struct {
+	path    string
+	Name    string         `json:"name"`
+	Scraper bool           `json:"scraper"`
+	Source  bool           `json:"source"`
+	Actions []pluginAction `json:"actions"`
+}
+
+type pluginAction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var plugins []*plugin
+
+func pluginDir() string {
+	return filepath.Join(configDir(), "plugins")
+}
+
+// loadPlugins describes every executable in dir. Plugins that fail to
+// describe themselves are skipped and reported in the returned errors.
+func loadPlugins(dir string) ([]*plugin, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	var loaded []*plugin
+	var errs []error
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+		p := &plugin{path: filepath.Join(dir, entry.Name())}
+		if err := p.call(context.Background(), "describe", struct{}{}, p); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if p.Name == "" {
+			p.Name = entry.Name()
+		}
+		loaded = append(loaded, p)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Name < loaded[j].Name })
+	return loaded, errs
+}
+
+func (p *plugin) call(ctx context.Context, command string, req, resp any) error {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.path, command)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	logger.Debug("plugin call", "plugin", p.path, "command", command, "request", string(input))
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("plugin %s %s: %v %s", filepath.Base(p.path), command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return fmt.Errorf("plugin %s %s: invalid response: %v", filepath.Base(p.path), command, err)
+	}
+	return nil
+}
+
+// pluginVideos collects the videos offered by source plugins.
+func pluginVideos(ctx context.Context) ([]string, []error) {
+	var videos []string
+	var errs []error
+	for _, p := range plugins {
+		if !p.Source {
+			continue
+		}
+		var resp struct {
+			Videos []string `json:"videos"`
+		}
+		if err := p.call(ctx, "list", struct{}{}, &resp); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		videos = append(videos, resp.Videos...)
+	}
+	return videos, errs
+}
+
+// scrapeMetadata asks every scraper plugin, then TMDB when an API key is
+// configured, about video and merges the answers, earlier plugins taking
+// precedence.
+func scrapeMetadata(ctx context.Context, video string) (*metadata, []error) {
+	var md *metadata
+	var errs []error
+	for _, p := range plugins {
+		if !p.Scraper {
+			continue
+		}
+		var found metadata
+		if err := p.call(ctx, "scrape", map[string]string{"path": video}, &found); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if md == nil {
+			md = &metadata{}
+		}
+		md.merge(found)
+	}
+	if cfg.TMDB.APIKey != "" {
+		found, err := tmdbMetadata(ctx, video)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			if md == nil {
+				md = &metadata{}
+			}
+			md.merge(found)
+		}
+	}
+	return md, errs
+}
+
+// runPluginAction runs a plugin action on video and returns its message.
+func runPluginAction(p *plugin, action, video string) (string, error) {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	// Actions are not cancelled with the rest of the launcher's work, since
+	// stopping one part way could leave its job half done.
+	err := p.call(context.Background(), "action", map[string]string{"action": action, "path": video}, &resp)
+	return resp.Message, err
+}
+
+// scrape fills in metadata for the videos matching keywords that have none
+// yet, using the installed scraper plugins. With --force it scrapes those
+// that have some again, for fields added to the scrapers since: what they
+// return now wins, and what they no longer return is kept. Interrupted, it
+// keeps what was scraped so far.
+func scrape(ctx context.Context, keywords []string, ix *index, st *state) error {
+	force := slices.Contains(keywords, "--force")
+	keywords = slices.DeleteFunc(slices.Clone(keywords), func(arg string) bool { return arg == "--force" })
+	videos, err := searchVideos(ctx, keywords, ix, st)
+	if err != nil {
+		return err
+	}
+	scraped := 0
+	for _, video := range videos {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted")
+			break
+		}
+		old := ix.metadata(video)
+		if old != nil && !force {
+			continue
+		}
+		md, errs := scrapeMetadata(ctx, video)
+		for _, err := range errs {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		if md == nil {
+			continue
+		}
+		if old != nil {
+			md.merge(*old)
+		}
+		ix.ensure(video).Metadata = md
+		scraped++
+		fmt.Printf("Scraped %s\n", displayPath(video))
+	}
+	fmt.Printf("Scraped metadata for %d of %d videos\n", scraped, len(videos))
+	return ix.save()
+}
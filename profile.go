@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// profileSettings is read from profile.json in the profile directory. A
+// restricted profile only sees videos under AllowDirs, carrying one of
+// AllowTags or rated one of AllowCertifications, and when PIN is set it
+// must be entered to quit.
+type profileSettings struct {
+	Restricted          bool     `json:"restricted"`
+	AllowDirs           []string `json:"allow_dirs,omitempty"`
+	AllowTags           []string `json:"allow_tags,omitempty"`
+	AllowCertifications []string `json:"allow_certifications,omitempty"`
+	PIN                 string   `json:"pin,omitempty"`
+}
+
+func loadProfileSettings() (profileSettings, error) {
+	var ps profileSettings
+	data, err := os.ReadFile(filepath.Join(profileDir(), "profile.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ps, nil
+	}
+	if err != nil {
+		return ps, err
+	}
+	err = json.Unmarshal(data, &ps)
+	return ps, err
+}
+
+// locked reports whether leaving the launcher requires the PIN.
+func (ps profileSettings) locked() bool {
+	return ps.Restricted && ps.PIN != ""
+}
+
+// allows reports whether the profile may see video.
+func (ps profileSettings) allows(video string, ix *index) bool {
+	if !ps.Restricted {
+		return true
+	}
+	for _, dir := range ps.AllowDirs {
+		// Relative directories are taken from the root video is under.
+		if !filepath.IsAbs(dir) {
+			root := rootOf(video)
+			if root == "" {
+				continue
+			}
+			dir = filepath.Join(root, dir)
+		}
+		if within(dir, video) {
+			return true
+		}
+	}
+	if matchesCertification(ix.certification(video), ps.AllowCertifications) {
+		return true
+	}
+	for _, allowed := range ps.AllowTags {
+		for _, tag := range ix.tags(video) {
+			if tag == normalizeTag(allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scanTickInterval is how often the progress of a running scan is redrawn.
+const scanTickInterval = 250 * time.Millisecond
+
+// scanProgress counts what a scan has covered so far. The scan updates it
+// while the UI reads it.
+type scanProgress struct {
+	start time.Time
+	dirs  atomic.Int64
+	files atomic.Int64
+}
+
+func newScanProgress() *scanProgress {
+	return &scanProgress{start: time.Now()}
+}
+
+// String describes the progress like "120 folders, 3400 files, 4s".
+func (p *scanProgress) String() string {
+	return fmt.Sprintf("%d folders, %d files, %s", p.dirs.Load(), p.files.Load(),
+		time.Since(p.start).Truncate(time.Second))
+}
+
+type scanTickMsg struct{}
+
+// scanTick redraws the scan progress shortly, even while no videos are
+// found, such as in a deep tree of folders.
+func scanTick() tea.Cmd {
+	return tea.Tick(scanTickInterval, func(time.Time) tea.Msg { return scanTickMsg{} })
+}
+
+// showProgress keeps the progress of a scan on stderr, if that is a
+// terminal, until the returned function is called. Scans finishing within
+// half a second show nothing.
+func showProgress(p *scanProgress) (stop func()) {
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-done:
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+		ticker := time.NewTicker(scanTickInterval)
+		defer ticker.Stop()
+		for {
+			fmt.Fprintf(os.Stderr, "\r\033[KScanning: %s", p)
+			select {
+			case <-done:
+				fmt.Fprint(os.Stderr, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// normalizedNames caches normalizedName, which is computed for every file on
+// each keystroke of the filter.
+var normalizedNames sync.Map
+
+// query is a parsed set of search words. Plain words must all appear in the
+// path or the cleaned-up name of the file (see normalizedName) and words
+// starting with "!" must not; "tag:" words must all be attached to the file
+// and "stars:" words compare against the viewer's rating (e.g. "stars:>=4"),
+// "rating:" words against the scraped community rating (e.g. "rating:>7.5")
+// and "year:" words against the release year (e.g. "year:80s",
+// "year:1990-1999" or "year:>=2010").
+// "ext:", "cert:" and "root:" words limit the results to those containers,
+// age ratings or library roots; a file matches any of them.
+type query struct {
+	terms   []string
+	exclude []string
+	tags    []string
+	stars   []comparison
+	ratings []comparison
+	years   []comparison
+	exts    []string
+	certs   []string
+	roots   []string
+	// metadata extends plain words to the scraped title, plot, genres and
+	// actors and to the tags of a file. Command-line keywords set it unless
+	// --path-only is given.
+	metadata bool
+	// fits limits the results to unwatched videos with a scraped runtime of
+	// at most this many minutes (--fits).
+	fits int
+}
+
+// parseBudget reads a --fits budget such as "100m", "1h40m" or "100" (in
+// minutes) and returns it in minutes.
+func parseBudget(s string) (int, error) {
+	if minutes, err := strconv.Atoi(s); err == nil && minutes > 0 {
+		return minutes, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid time budget %q, use e.g. 100m or 1h40m", s)
+	}
+	return int(d / time.Minute), nil
+}
+
+// comparison is a numeric condition such as ">=4" or "3".
+type comparison struct {
+	op    string
+	value float64
+}
+
+func parseComparison(s string) (comparison, bool) {
+	c := comparison{op: "="}
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			c.op = op
+			s = strings.TrimPrefix(s, op)
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return c, false
+	}
+	c.value = v
+	return c, true
+}
+
+// parseYears reads a year condition: a comparison such as ">=2010", a range
+// such as "1990-1999" or a decade such as "1980s" or "80s" (20s and earlier
+// are this century).
+func parseYears(s string) ([]comparison, bool) {
+	if decade, ok := strings.CutSuffix(s, "s"); ok {
+		start, err := strconv.Atoi(decade)
+		if err != nil || start%10 != 0 {
+			return nil, false
+		}
+		if len(decade) == 2 {
+			start += 1900
+			if start < 1930 {
+				start += 100
+			}
+		}
+		s = fmt.Sprintf("%d-%d", start, start+9)
+	}
+	if from, to, ok := strings.Cut(s, "-"); ok {
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil {
+			return nil, false
+		}
+		return []comparison{{">=", float64(lo)}, {"<=", float64(hi)}}, true
+	}
+	c, ok := parseComparison(s)
+	return []comparison{c}, ok
+}
+
+func (c comparison) matches(v float64) bool {
+	switch c.op {
+	case ">=":
+		return v >= c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	case "<":
+		return v < c.value
+	}
+	return v == c.value
+}
+
+func parseQuery(words []string) query {
+	var q query
+	for _, w := range words {
+		w = strings.ToLower(w)
+		switch {
+		case strings.HasPrefix(w, "tag:"):
+			if tag := normalizeTag(strings.TrimPrefix(w, "tag:")); tag != "" {
+				q.tags = append(q.tags, tag)
+			}
+		case strings.HasPrefix(w, "ext:"):
+			for _, ext := range strings.Split(strings.TrimPrefix(w, "ext:"), ",") {
+				if ext = strings.TrimPrefix(ext, "."); ext != "" {
+					q.exts = append(q.exts, "."+ext)
+				}
+			}
+		case strings.HasPrefix(w, "cert:"):
+			for _, cert := range strings.Split(strings.TrimPrefix(w, "cert:"), ",") {
+				if cert != "" {
+					q.certs = append(q.certs, cert)
+				}
+			}
+		case strings.HasPrefix(w, "root:"):
+			if root := strings.TrimPrefix(w, "root:"); root != "" {
+				q.roots = append(q.roots, root)
+			}
+		case strings.HasPrefix(w, "stars:"):
+			if c, ok := parseComparison(strings.TrimPrefix(w, "stars:")); ok {
+				q.stars = append(q.stars, c)
+			}
+		case strings.HasPrefix(w, "rating:"):
+			if c, ok := parseComparison(strings.TrimPrefix(w, "rating:")); ok {
+				q.ratings = append(q.ratings, c)
+			}
+		case strings.HasPrefix(w, "year:"):
+			if cs, ok := parseYears(strings.TrimPrefix(w, "year:")); ok {
+				q.years = append(q.years, cs...)
+			}
+		case strings.HasPrefix(w, "!"):
+			if term := strings.TrimPrefix(w, "!"); term != "" {
+				q.exclude = append(q.exclude, term)
+			}
+		case w != "":
+			q.terms = append(q.terms, w)
+		}
+	}
+	return q
+}
+
+func (q query) matches(path string, ix *index, st *state) bool {
+	lowerPath := strings.ToLower(path)
+	var fields string
+	if q.metadata {
+		fields = metadataText(path, ix)
+	}
+	contains := func(term string) bool {
+		return strings.Contains(lowerPath, term) || strings.Contains(normalizedName(path), term) ||
+			strings.Contains(fields, term)
+	}
+	for _, term := range q.terms {
+		if !contains(term) {
+			return false
+		}
+	}
+	for _, term := range q.exclude {
+		if contains(term) {
+			return false
+		}
+	}
+	for _, c := range q.stars {
+		if !c.matches(float64(st.Ratings[path])) {
+			return false
+		}
+	}
+	if len(q.ratings) > 0 {
+		var rating float64
+		if md := ix.metadata(path); md != nil {
+			rating = md.Rating
+		}
+		for _, c := range q.ratings {
+			if !c.matches(rating) {
+				return false
+			}
+		}
+	}
+	if len(q.years) > 0 {
+		_, year := titleYear(path, ix)
+		for _, c := range q.years {
+			if !c.matches(float64(year)) {
+				return false
+			}
+		}
+	}
+	if q.fits > 0 {
+		md := ix.metadata(path)
+		if md == nil || md.Runtime == 0 || md.Runtime > q.fits || !st.Watched[path].IsZero() {
+			return false
+		}
+	}
+	if len(q.exts) > 0 && !q.matchesExt(lowerPath) {
+		return false
+	}
+	if len(q.certs) > 0 && !matchesCertification(ix.certification(path), q.certs) {
+		return false
+	}
+	if len(q.roots) > 0 && !q.matchesRoot(path) {
+		return false
+	}
+	if len(q.tags) == 0 {
+		return true
+	}
+	tags := ix.tags(path)
+	for _, want := range q.tags {
+		found := false
+		for _, t := range tags {
+			if t == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (q query) matchesExt(lowerPath string) bool {
+	ext := filepath.Ext(lowerPath)
+	for _, want := range q.exts {
+		if ext == want {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCertification reports whether cert is one of the age ratings in
+// certs, ignoring case.
+func matchesCertification(cert string, certs []string) bool {
+	for _, want := range certs {
+		if cert != "" && strings.EqualFold(cert, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoot reports whether path is under a root whose name starts with
+// one of the "root:" words.
+func (q query) matchesRoot(path string) bool {
+	root := rootOf(path)
+	if root == "" {
+		return false
+	}
+	name := strings.ToLower(rootName(root))
+	for _, want := range q.roots {
+		if strings.HasPrefix(name, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedName is the lowercased title and year cleaned from the file name
+// of path, so that "blade runner 2049" matches
+// "Blade.Runner.2049.2017.2160p.mkv" even though the path has dots.
+func normalizedName(path string) string {
+	if name, ok := normalizedNames.Load(path); ok {
+		return name.(string)
+	}
+	title, year := cleanTitle(path)
+	name := strings.ToLower(title)
+	if year > 0 {
+		name += fmt.Sprintf(" %d", year)
+	}
+	normalizedNames.Store(path, name)
+	return name
+}
+
+// metadataText joins the indexed title, plot, genres, actors and tags of
+// path, and any title or show set by a folder's .movie-launcher.toml, into
+// one lowercased string for keyword matching.
+func metadataText(path string, ix *index) string {
+	var fields []string
+	if o := overrideFor(path); o.Title != "" || o.Show != "" {
+		fields = append(fields, o.Title, o.Show)
+	}
+	e := ix.entry(path)
+	if e == nil {
+		return strings.ToLower(strings.Join(fields, "\n"))
+	}
+	fields = append(fields, e.Tags...)
+	if md := e.Metadata; md != nil {
+		fields = append(fields, md.Title, md.Plot)
+		fields = append(fields, md.Genres...)
+		fields = append(fields, md.Actors...)
+	}
+	return strings.ToLower(strings.Join(fields, "\n"))
+}
+
+// splitWords splits a filter into words like strings.Fields, except that
+// double-quoted phrases such as "blade runner" (or !"blade runner") stay
+// together.
+func splitWords(s string) []string {
+	var words []string
+	prefix := ""
+	for i, part := range strings.Split(s, `"`) {
+		if i%2 == 1 {
+			if part = strings.TrimSpace(part); part != "" {
+				words = append(words, prefix+part)
+			}
+			continue
+		}
+		fields := strings.Fields(part)
+		prefix = ""
+		if n := len(fields); n > 0 && fields[n-1] == "!" && !strings.HasSuffix(part, " ") {
+			prefix, fields = "!", fields[:n-1]
+		}
+		words = append(words, fields...)
+	}
+	return words
+}
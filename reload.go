@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configPollInterval is how often config.toml is checked for changes.
+const configPollInterval = 2 * time.Second
+
+// applyConfigFlags reapplies the command-line flags that take precedence
+// over config.toml. main sets it so a reloaded config keeps them.
+var applyConfigFlags = func(c *config) error { return nil }
+
+func configPath() string {
+	return filepath.Join(configDir(), "config.toml")
+}
+
+// configModTime returns when config.toml was last changed, or the zero time
+// if there is none.
+func configModTime() time.Time {
+	info, err := os.Stat(configPath())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// configMsg is the result of checking config.toml. cfg is set when it
+// changed since modTime and was read successfully, err when it could not
+// be read.
+type configMsg struct {
+	modTime time.Time
+	cfg     *config
+	err     error
+}
+
+// watchConfig checks config.toml for changes after configPollInterval.
+func watchConfig(last time.Time) tea.Cmd {
+	return tea.Tick(configPollInterval, func(time.Time) tea.Msg {
+		modTime := configModTime()
+		if modTime.Equal(last) {
+			return configMsg{modTime: last}
+		}
+		c, err := loadConfig(configPath())
+		if err != nil {
+			return configMsg{modTime: modTime, err: err}
+		}
+		return configMsg{modTime: modTime, cfg: &c}
+	})
+}
+
+// reloadConfig applies a changed config.toml. When it changes what the scan
+// finds, such as images or follow_symlinks, the library is scanned again.
+func (m *model) reloadConfig(msg configMsg) tea.Cmd {
+	m.configTime = msg.modTime
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error reloading config: %v", msg.err)
+		return nil
+	}
+	if msg.cfg == nil {
+		return nil
+	}
+	if err := applyConfigFlags(msg.cfg); err != nil {
+		m.status = fmt.Sprintf("Error reloading config: %v", err)
+		return nil
+	}
+	rescan := msg.cfg.walkOptions() != cfg.walkOptions() || msg.cfg.Images != cfg.Images
+	cfg = *msg.cfg
+	setLanguage(cfg.Language)
+	m.searchInput.Placeholder = tr("filter...")
+	m.tagInput.Placeholder = tr("tag (prefix with - to remove)...")
+	m.pinInput.Placeholder = tr("PIN to exit...")
+	m.paletteInput.Placeholder = tr("command...")
+	m.playlistInput.Placeholder = tr("playlist file...")
+	logger.Info("config reloaded", "path", configPath())
+	m.status = "Config reloaded"
+	if !rescan {
+		return nil
+	}
+	if m.scanning {
+		m.status = "Config reloaded; scan settings apply from the next scan"
+		return nil
+	}
+	m.allVideos, m.videos = nil, nil
+	m.scan, m.progress = startScan(m.ctx, true)
+	m.scanning = true
+	m.status = "Config reloaded, rescanning..."
+	return tea.Batch(waitForScan(m.scan), scanTick())
+}
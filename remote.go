@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// target is a [[target]] table in config.toml: a machine that plays videos
+// over SSH, e.g. the HTPC connected to the TV, while the list runs locally.
+type target struct {
+	Name string `toml:"name"`
+	// Host is the ssh destination, such as "htpc" or "me@10.0.0.5".
+	Host string `toml:"host"`
+	// Player is the player command on the remote machine (default mpv).
+	Player string   `toml:"player"`
+	Args   []string `toml:"args"`
+	// Paths translate local library paths to where the same files are
+	// mounted on the remote machine.
+	Paths []pathMapping `toml:"path_map"`
+}
+
+type pathMapping struct {
+	Local  string `toml:"local"`
+	Remote string `toml:"remote"`
+}
+
+// remoteTarget is the target chosen with --target (or target in the config),
+// nil when videos play locally.
+var remoteTarget *target
+
+// findTarget returns the configured target called name.
+func findTarget(c config, name string) (*target, error) {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return &c.Targets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no [[target]] named %q in the config", name)
+}
+
+// remotePath maps video to its path on the target using the longest
+// matching local prefix. Videos outside every mapping, such as URLs, are
+// passed on unchanged.
+func (t *target) remotePath(video string) string {
+	var best *pathMapping
+	for i, m := range t.Paths {
+		if within(filepath.Clean(m.Local), video) && (best == nil || len(m.Local) > len(best.Local)) {
+			best = &t.Paths[i]
+		}
+	}
+	if best == nil {
+		return video
+	}
+	rel, err := filepath.Rel(filepath.Clean(best.Local), video)
+	if err != nil {
+		return video
+	}
+	return path.Join(best.Remote, filepath.ToSlash(rel))
+}
+
+// command returns the ssh invocation that plays video on the target. The
+// remote command line goes through the remote shell, so every word is
+// quoted. In detached mode the player must not grab the local terminal.
+func (t *target) command(video string) (string, []string) {
+	player := t.Player
+	if player == "" {
+		player = "mpv"
+	}
+	words := append([]string{player}, t.Args...)
+	words = append(words, t.remotePath(video))
+	for i, word := range words {
+		words[i] = shellQuote(word)
+	}
+	tty := "-t"
+	if detachPlayer {
+		tty = "-n"
+	}
+	return "ssh", []string{tty, t.Host, strings.Join(words, " ")}
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
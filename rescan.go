@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// rescanMsg carries the videos found by walking dir again.
+type rescanMsg struct {
+	dir    string
+	videos []string
+	err    error
+}
+
+// rescanFolder walks dir again in the background, without trusting the
+// cached directory times, and records what it found in the library cache
+// in place of what was cached below dir.
+func rescanFolder(ctx context.Context, dir string) tea.Cmd {
+	return func() tea.Msg {
+		clearOverrides()
+		var videos []string
+		opts := cfg.walkOptions()
+		opts.ctx = ctx
+		dirs, err := walkFiles(dir, opts, nil, func(path string) {
+			if isLibraryFile(path) {
+				videos = append(videos, path)
+			}
+		})
+		if errors.Is(err, fs.ErrNotExist) {
+			// Everything in the folder was moved away along with it.
+			err = nil
+		}
+		if err != nil {
+			return rescanMsg{dir: dir, err: err}
+		}
+		cache := loadLibraryCache(libraryCachePath())
+		if rc := cache.Roots[rootOf(dir)]; rc != nil {
+			kept := rc.Videos.filter(func(video string) bool { return !within(dir, video) })
+			rc.Videos = append(kept, newVideoList(videos)...)
+			maps.DeleteFunc(rc.Dirs, func(path string, _ *dirState) bool { return within(dir, path) })
+			if rc.Dirs == nil {
+				rc.Dirs = map[string]*dirState{}
+			}
+			maps.Copy(rc.Dirs, dirs)
+			saveLibraryCache(cache)
+		}
+		return rescanMsg{dir: dir, videos: videos}
+	}
+}
+
+// rescan starts rescanning the folder of the selected video.
+func (m *model) rescan() tea.Cmd {
+	if len(m.videos) == 0 {
+		return nil
+	}
+	video := m.videos[m.cursor]
+	if rootOf(video) == "" {
+		m.status = "Only folders in the library can be rescanned"
+		return nil
+	}
+	if m.scanning {
+		m.status = "Wait for the scan to finish"
+		return nil
+	}
+	dir := filepath.Dir(video)
+	m.status = "Rescanning " + displayPath(dir) + "..."
+	return rescanFolder(m.ctx, dir)
+}
+
+// applyRescan replaces the listed videos below msg.dir with those the
+// rescan found, where the folder was in the list, and looks among the new
+// ones for moved files.
+func (m *model) applyRescan(msg rescanMsg) tea.Cmd {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error rescanning %s: %v", displayPath(msg.dir), msg.err)
+		return nil
+	}
+	at := -1
+	old := map[string]bool{}
+	var kept []string
+	for _, video := range m.allVideos {
+		if !within(msg.dir, video) {
+			kept = append(kept, video)
+			continue
+		}
+		if at < 0 {
+			at = len(kept)
+		}
+		old[video] = true
+		delete(m.sizes, video)
+		delete(m.mtimes, video)
+	}
+	if at < 0 {
+		at = len(kept)
+	}
+	var found, added []string
+	for _, video := range msg.videos {
+		if !m.query.matches(video, m.index, m.state) || !m.settings.allows(video, m.index) {
+			continue
+		}
+		m.label(video)
+		found = append(found, video)
+		if old[video] {
+			delete(old, video)
+		} else {
+			added = append(added, video)
+		}
+	}
+	m.allVideos = slices.Insert(kept, at, found...)
+	m.refresh()
+	m.status = fmt.Sprintf("Rescanned %s: %d new, %d gone", displayPath(msg.dir), len(added), len(old))
+	return m.findMoves(added)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// videoDirs are the library roots listed in VIDEO_DIR, separated like PATH
+// (":" on Unix, ";" on Windows).
+var videoDirs []string
+
+func parseRoots(list string) []string {
+	var roots []string
+	for _, dir := range filepath.SplitList(list) {
+		if dir != "" {
+			roots = append(roots, filepath.Clean(dir))
+		}
+	}
+	return roots
+}
+
+// within reports whether path is dir or lies below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// rootOf returns the library root video was found under, or "" for videos
+// from elsewhere such as source plugins.
+func rootOf(video string) string {
+	var found string
+	for _, root := range videoDirs {
+		if within(root, video) && len(root) > len(found) {
+			found = root
+		}
+	}
+	return found
+}
+
+// rootName is how a root is shown and matched by "root:" filters.
+func rootName(root string) string {
+	return filepath.Base(root)
+}
+
+// rootCounts counts the listed videos under each root.
+func (m model) rootCounts() map[string]int {
+	counts := map[string]int{}
+	for _, video := range filterVideos(m.allVideos, m.filter, m.index, m.state) {
+		counts[rootOf(video)]++
+	}
+	return counts
+}
+
+func (m model) updateRoots(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch keys().command("roots", msg.String()) {
+	case "up":
+		if m.rootCursor > 0 {
+			m.rootCursor--
+		}
+	case "down":
+		if m.rootCursor < len(videoDirs) {
+			m.rootCursor++
+		}
+	case "choose":
+		m.rootMode = false
+		// The first entry stands for all roots.
+		m.root = ""
+		m.status = "Showing all roots"
+		if m.rootCursor > 0 {
+			m.root = videoDirs[m.rootCursor-1]
+			m.status = "Showing " + m.root
+		}
+		m.refresh()
+	case "close":
+		m.rootMode = false
+	}
+	return m, nil
+}
+
+func (m model) viewRoots() string {
+	counts := m.rootCounts()
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	s := keys().help("Library roots - %s to limit the list to one, %s to cancel", "roots", "choose", "close") + "\n\n"
+	lines := []string{fmt.Sprintf("%s (%d)", tr("All roots"), total)}
+	for _, root := range videoDirs {
+		lines = append(lines, fmt.Sprintf("%s%s (%d)", icon("folder"), root, counts[root]))
+	}
+	for i, line := range lines {
+		line = truncateMiddle(line, m.width)
+		if i == m.rootCursor {
+			s += selectedStyle.Render(line) + "\n"
+		} else {
+			s += line + "\n"
+		}
+	}
+	return s
+}
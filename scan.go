@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scanBatchInterval is how often newly found videos are handed to the UI.
+const scanBatchInterval = 50 * time.Millisecond
+
+// scanBatchMsg carries videos found since the previous batch. The last batch
+// of a scan has done set, along with any error that ended it and the roots
+// that were offline. A batch with replace set holds the whole library,
+// replacing what was listed so far.
+type scanBatchMsg struct {
+	videos  []string
+	done    bool
+	replace bool
+	offline []string
+	err     error
+}
+
+// scanLibrary calls found for every video below the library roots. A root
+// that cannot be read does not stop the others from being scanned. When
+// cache is not nil, only directories modified since the cached walk are
+// read, and the file list of every root walked without error is recorded
+// in it. A root that cannot be reached at all, such as a NAS that is turned
+// off, then lists its cached files instead and is returned as offline.
+// progress, if not nil, counts the folders read and videos found. Once ctx
+// is cancelled no more directories are read and ctx's error is returned.
+func scanLibrary(ctx context.Context, found func(path string), cache *libraryCache, progress *scanProgress) (offline []string, err error) {
+	var errs []error
+	opts := cfg.walkOptions()
+	opts.progress = progress
+	opts.ctx = ctx
+	for _, root := range videoDirs {
+		if ctx.Err() != nil {
+			return offline, ctx.Err()
+		}
+		start := time.Now()
+		var prev map[string]*dirState
+		if cache != nil && cache.Roots[root] != nil {
+			prev = cache.Roots[root].Dirs
+		}
+		var videos []string
+		dirs, err := walkFiles(root, opts, prev, func(path string) {
+			if !isLibraryFile(path) {
+				logger.Debug("skipped", "path", path, "reason", "not a video")
+				return
+			}
+			videos = append(videos, path)
+			if progress != nil {
+				progress.files.Add(1)
+			}
+			found(path)
+		})
+		logger.Info("scan finished", "root", root, "videos", len(videos), "duration", time.Since(start))
+		var rc *rootCache
+		if cache != nil {
+			rc = cache.Roots[root]
+		}
+		switch {
+		case ctx.Err() != nil:
+			return offline, ctx.Err()
+		case err != nil && rc != nil && len(videos) == 0:
+			logger.Warn("root offline, listing cached files", "root", root, "err", err)
+			for _, video := range rc.Videos.paths() {
+				found(video)
+			}
+			offline = append(offline, root)
+		case err != nil:
+			errs = append(errs, err)
+		case cache != nil:
+			cache.Roots[root] = &rootCache{Scanned: time.Now(), Videos: newVideoList(videos), Dirs: dirs}
+		}
+	}
+	return offline, errors.Join(errs...)
+}
+
+// isLibraryFile reports whether the scan lists path: only video files, and
+// photos when asked to.
+func isLibraryFile(path string) bool {
+	return isVideoFile(path) || cfg.Images && isImageFile(path)
+}
+
+// startScan lists the library and queries the source plugins in the
+// background, streaming every video found in batches. Matching against the
+// search keywords happens in the UI, which owns the index and state.
+//
+// Depending on the refresh policy the cached file list is used instead of
+// walking the library, or shown straight away while a walk runs in the
+// background and replaces it when done. The returned progress counts what
+// the walk has covered. Cancelling ctx stops the scan, and the channel is
+// closed without a final batch.
+func startScan(ctx context.Context, reindex bool) (<-chan scanBatchMsg, *scanProgress) {
+	clearOverrides()
+	ch := make(chan scanBatchMsg, 16)
+	progress := newScanProgress()
+	send := func(msg scanBatchMsg) {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(ch)
+		defer catchPanic()
+		cache := loadLibraryCache(libraryCachePath())
+		if reindex {
+			// Read every directory again rather than trusting mtimes.
+			for _, rc := range cache.Roots {
+				rc.Dirs = nil
+			}
+		}
+		if useCache(cache, reindex) {
+			cached, _ := cache.videos()
+			if cfg.Refresh != "background" {
+				send(scanBatchMsg{videos: append(cached, sourceVideos(ctx)...), done: true})
+				return
+			}
+			send(scanBatchMsg{videos: cached})
+			var videos []string
+			offline, err := scanLibrary(ctx, func(path string) { videos = append(videos, path) }, cache, progress)
+			saveLibraryCache(cache)
+			if ctx.Err() != nil {
+				return
+			}
+			send(scanBatchMsg{videos: append(videos, sourceVideos(ctx)...), done: true, replace: true, offline: offline, err: err})
+			return
+		}
+
+		var batch []string
+		last := time.Now()
+		offline, err := scanLibrary(ctx, func(path string) {
+			batch = append(batch, path)
+			if time.Since(last) >= scanBatchInterval {
+				send(scanBatchMsg{videos: batch})
+				batch = nil
+				last = time.Now()
+			}
+		}, cache, progress)
+		saveLibraryCache(cache)
+		if ctx.Err() != nil {
+			return
+		}
+		send(scanBatchMsg{videos: append(batch, sourceVideos(ctx)...), done: true, offline: offline, err: err})
+	}()
+	return ch, progress
+}
+
+// sourceVideos returns the videos offered by source plugins.
+func sourceVideos(ctx context.Context) []string {
+	videos, errs := pluginVideos(ctx)
+	for _, err := range errs {
+		logger.Warn("source plugin failed", "err", err)
+	}
+	return videos
+}
+
+func saveLibraryCache(cache *libraryCache) {
+	if err := cache.save(); err != nil {
+		logger.Warn("saving library cache failed", "err", err)
+	}
+}
+
+// readList reads one path per line from r, as given to --stdin, keeping
+// videos (and photos when enabled) and URLs. Relative paths are made
+// absolute so history and ratings stay attached to the file.
+func readList(r io.Reader) ([]string, error) {
+	var videos []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.Contains(line, "://"):
+			videos = append(videos, line)
+		case isVideoFile(line) || (cfg.Images && isImageFile(line)):
+			if abs, err := filepath.Abs(line); err == nil {
+				line = abs
+			}
+			videos = append(videos, line)
+		default:
+			logger.Debug("skipped", "path", line, "reason", "not a video")
+		}
+	}
+	return videos, scanner.Err()
+}
+
+// scanList hands videos to the UI as a single, final scan batch, so they go
+// through the same keyword and profile filtering as scanned ones.
+func scanList(videos []string) <-chan scanBatchMsg {
+	ch := make(chan scanBatchMsg, 1)
+	ch <- scanBatchMsg{videos: videos, done: true}
+	close(ch)
+	return ch
+}
+
+func waitForScan(ch <-chan scanBatchMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// demotesJunk reports whether adding videos to the end of the list would
+// put them out of place because junk is listed last.
+func (m *model) demotesJunk(videos []string) bool {
+	if cfg.Junk == "off" {
+		return false
+	}
+	if n := len(m.videos); n > 0 && isJunk(m.videos[n-1]) {
+		return true
+	}
+	return slices.ContainsFunc(videos, isJunk)
+}
+
+// addScanned merges a batch of scanned videos into the list, keeping those
+// that match the search keywords and that the profile may see. In the common
+// unfiltered, unsorted case the batch is appended without rebuilding the
+// whole list, which keeps huge libraries responsive while they stream in.
+func (m *model) addScanned(videos []string) {
+	var added []string
+	for _, video := range videos {
+		if m.query.matches(video, m.index, m.state) && m.settings.allows(video, m.index) {
+			m.label(video)
+			added = append(added, video)
+		}
+	}
+	m.allVideos = append(m.allVideos, added...)
+	if m.filter != "" || m.root != "" || sortModes[m.sortMode] != "path" || m.demotesJunk(added) || slices.ContainsFunc(added, m.state.isPinned) || slices.ContainsFunc(added, isPart) || m.addsEdition(added) {
+		m.refresh()
+		return
+	}
+	for _, video := range added {
+		if m.showHidden || !m.state.isHidden(video) {
+			m.videos = append(m.videos, video)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// display is a screen the player can open on, numbered the way players
+// count them.
+type display struct {
+	index int
+	name  string
+}
+
+// pickedScreen is the display chosen with "D" for this session: nil keeps
+// the screen setting and -1 leaves the choice to the player.
+var pickedScreen *int
+
+// playerScreen returns the display the player opens on, or nil to leave it
+// to the player.
+func playerScreen() *int {
+	if pickedScreen != nil {
+		if *pickedScreen < 0 {
+			return nil
+		}
+		return pickedScreen
+	}
+	return cfg.Screen
+}
+
+// listDisplays asks xrandr for the connected monitors. Without it (or on a
+// platform it does not know) it offers the first two screens by number.
+func listDisplays() []display {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "xrandr", "--listmonitors").Output()
+	if err != nil {
+		logger.Debug("listing displays failed", "err", err)
+		return []display{{index: 0}, {index: 1}}
+	}
+	// Lines look like " 1: +HDMI-1 1920/531x1080/299+1920+0  HDMI-1".
+	var displays []display
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		before, after, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		index, err := strconv.Atoi(before)
+		if !ok || err != nil {
+			continue
+		}
+		d := display{index: index}
+		if fields := strings.Fields(after); len(fields) > 0 {
+			d.name = fields[len(fields)-1]
+		}
+		displays = append(displays, d)
+	}
+	return displays
+}
+
+// screenArgs returns the arguments that open player on screen, for the
+// players that have them.
+func screenArgs(player string, screen int) []string {
+	n := strconv.Itoa(screen)
+	switch playerName(player) {
+	case "mpv":
+		return []string{"--screen=" + n, "--fs-screen=" + n}
+	case "vlc", "cvlc":
+		return []string{"--qt-fullscreen-screennumber=" + n}
+	case "mplayer":
+		return []string{"-xineramascreen", n}
+	}
+	return nil
+}
+
+// cycleScreen moves the player to the next display for the rest of the
+// session, after the last one back to the player's own choice.
+func (m *model) cycleScreen() {
+	if m.displays == nil {
+		m.displays = listDisplays()
+	}
+	current := -1
+	if s := playerScreen(); s != nil {
+		current = *s
+	}
+	next := -1
+	for i, d := range m.displays {
+		if d.index == current {
+			if i+1 < len(m.displays) {
+				next = m.displays[i+1].index
+			}
+			break
+		}
+		if current < 0 && i == 0 {
+			next = d.index
+			break
+		}
+	}
+	pickedScreen = &next
+	if next < 0 {
+		m.status = "The player opens on the display it chooses"
+		return
+	}
+	m.status = fmt.Sprintf("The player opens on display %d", next)
+	for _, d := range m.displays {
+		if d.index == next && d.name != "" {
+			m.status += " (" + d.name + ")"
+		}
+	}
+}
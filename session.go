@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// session records where the list was left so the next launch of the
+// profile can pick up from there. For keyword searches the keywords and
+// results are kept too, so --resume-session can reopen them without
+// scanning the library again.
+type session struct {
+	Filter    string   `json:"filter,omitempty"`
+	Sort      string   `json:"sort,omitempty"`
+	ThenSort  string   `json:"then_sort,omitempty"`
+	Video     string   `json:"video,omitempty"`
+	Dashboard bool     `json:"dashboard,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	Results   []string `json:"results,omitempty"`
+	// Folders are the filter and sort order of the folder view, which
+	// are kept apart from the list's.
+	Folders *viewSettings `json:"folders,omitempty"`
+}
+
+// viewSettings are the filter and sort order of a view. The empty value is
+// no filter, sorted by path.
+type viewSettings struct {
+	Filter   string `json:"filter,omitempty"`
+	Sort     string `json:"sort,omitempty"`
+	ThenSort string `json:"then_sort,omitempty"`
+}
+
+// session captures the current filter, sort order and selected video.
+func (m model) session() *session {
+	list, folders := m.viewSettings(), m.otherView
+	if m.treeMode {
+		list, folders = folders, list
+	}
+	s := &session{
+		Filter:    list.Filter,
+		Sort:      list.Sort,
+		ThenSort:  list.ThenSort,
+		Dashboard: m.dashboard,
+	}
+	if folders != (viewSettings{}) {
+		s.Folders = &folders
+	}
+	if m.cursor < len(m.videos) {
+		s.Video = m.videos[m.cursor]
+	}
+	return s
+}
+
+// viewSettings returns the filter and sort order in use.
+func (m model) viewSettings() viewSettings {
+	return viewSettings{
+		Filter:   m.filter,
+		Sort:     sortModes[m.sortMode],
+		ThenSort: sortModes[m.thenMode],
+	}
+}
+
+// useViewSettings switches to the filter and sort order of v, without
+// refreshing the list.
+func (m *model) useViewSettings(v viewSettings) {
+	m.filter = v.Filter
+	m.searchInput.SetValue(v.Filter)
+	m.sortMode, m.thenMode = 0, 0
+	for i, mode := range sortModes {
+		if mode == v.Sort {
+			m.sortMode = i
+		}
+		if mode == v.ThenSort {
+			m.thenMode = i
+		}
+	}
+}
+
+// swapViewSettings puts the filter and sort order kept for the view being
+// opened in use, keeping those of the view being left, as the folder view
+// opens or closes.
+func (m *model) swapViewSettings() {
+	left := m.viewSettings()
+	m.useViewSettings(m.otherView)
+	m.otherView = left
+	m.refresh()
+}
+
+// restoreSession reapplies a saved session. The selected video may not have
+// been scanned yet, so the cursor moves to it once it shows up unless a key
+// is pressed first.
+func (m *model) restoreSession(s *session) {
+	m.useViewSettings(viewSettings{Filter: s.Filter, Sort: s.Sort, ThenSort: s.ThenSort})
+	if s.Folders != nil {
+		m.otherView = *s.Folders
+	}
+	m.restore = s.Video
+	m.refresh()
+	m.restoreCursor()
+}
+
+// restoreCursor moves the cursor to the video left selected last session
+// once it is listed.
+func (m *model) restoreCursor() {
+	if m.restore == "" {
+		return
+	}
+	for i, video := range m.videos {
+		if video == m.restore {
+			m.cursor = i
+			m.restore = ""
+			m.clampViewport()
+			return
+		}
+	}
+}
+
+// results returns the saved search results that are still available. URLs
+// from source plugins are kept as they cannot be checked.
+func (s *session) results() []string {
+	var results []string
+	for _, video := range s.Results {
+		if strings.Contains(video, "://") {
+			results = append(results, video)
+		} else if _, err := os.Stat(video); err == nil {
+			results = append(results, video)
+		}
+	}
+	return results
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// skipRule is a [[skip]] table. For the videos it matches (like a
+// [[player]] rule), mpv seeks past the intro between Intro[0] and Intro[1],
+// the last Outro of the video and every chapter whose title matches one of
+// the Chapters globs, ignoring case.
+type skipRule struct {
+	Match    string     `toml:"match"`
+	Intro    []duration `toml:"intro"`
+	Outro    duration   `toml:"outro"`
+	Chapters []string   `toml:"chapters"`
+}
+
+// skipsChapter reports whether the chapter titled title is to be skipped.
+func (r skipRule) skipsChapter(title string) bool {
+	for _, pattern := range r.Chapters {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(title)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skipper seeks past the intro, outro and skipped chapters of a video as
+// mpv reports the playback position. Each part is skipped once, so seeking
+// back into it plays it.
+type skipper struct {
+	rule     skipRule
+	duration float64
+	chapter  int
+	chapters []chapter
+	skipped  map[string]bool
+}
+
+// skipProperties are the mpv properties a skipper follows.
+var skipProperties = []string{"time-pos", "duration", "chapter", "chapter-list"}
+
+// newSkipper returns a skipper for video, or nil if no [[skip]] rule
+// matches it.
+func newSkipper(video string) *skipper {
+	for _, rule := range cfg.Skip {
+		if matchesVideo(rule.Match, video) {
+			return &skipper{rule: rule, chapter: -1, skipped: map[string]bool{}}
+		}
+	}
+	return nil
+}
+
+// once reports whether part has not been skipped yet, and marks it skipped.
+func (s *skipper) once(part string) bool {
+	if s.skipped[part] {
+		return false
+	}
+	s.skipped[part] = true
+	return true
+}
+
+// handle follows ev and returns the mpv command that skips ahead, or nil.
+func (s *skipper) handle(ev mpvEvent) []any {
+	if ev.Event != "property-change" {
+		return nil
+	}
+	switch ev.Name {
+	case "duration":
+		json.Unmarshal(ev.Data, &s.duration)
+	case "chapter-list":
+		s.chapters = nil
+		json.Unmarshal(ev.Data, &s.chapters)
+		return s.skipChapter()
+	case "chapter":
+		s.chapter = -1
+		json.Unmarshal(ev.Data, &s.chapter)
+		return s.skipChapter()
+	case "time-pos":
+		var pos float64
+		if json.Unmarshal(ev.Data, &pos) != nil {
+			return nil
+		}
+		if in := s.rule.Intro; len(in) == 2 && pos >= in[0].Seconds() && pos < in[1].Seconds() && s.once("intro") {
+			logger.Debug("skipping intro", "to", in[1].Seconds())
+			return []any{"seek", in[1].Seconds(), "absolute"}
+		}
+		if out := s.rule.Outro.Seconds(); out > 0 && s.duration > out && pos >= s.duration-out && s.once("outro") {
+			logger.Debug("skipping outro", "at", pos)
+			return []any{"seek", 100, "absolute-percent"}
+		}
+	}
+	return nil
+}
+
+// skipChapter leaves the current chapter if its title is skipped, for the
+// next chapter or, from the last one, the end of the video.
+func (s *skipper) skipChapter() []any {
+	ch := s.chapter
+	if ch < 0 || ch >= len(s.chapters) || !s.rule.skipsChapter(s.chapters[ch].Title) || !s.once(fmt.Sprintf("chapter %d", ch)) {
+		return nil
+	}
+	logger.Debug("skipping chapter", "title", s.chapters[ch].Title)
+	if ch+1 < len(s.chapters) {
+		return []any{"set_property", "chapter", ch + 1}
+	}
+	return []any{"seek", 100, "absolute-percent"}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxHistory caps the number of watch history entries kept per profile.
+const maxHistory = 500
+
+// state holds a profile's personal data: ratings, watch history, which files
+// were watched to the end, the files or folders hidden from normal views or
+// pinned to the top of them and where the list was left.
+type state struct {
+	path string
+	// loaded is the file as it was loaded or last saved, which save merges
+	// the changes made since into what other instances saved meanwhile.
+	loaded  []byte
+	Ratings map[string]int       `json:"ratings,omitempty"`
+	History []historyEntry       `json:"history,omitempty"`
+	Watched map[string]time.Time `json:"watched,omitempty"`
+	Hidden  map[string]bool      `json:"hidden,omitempty"`
+	Pinned  map[string]bool      `json:"pinned,omitempty"`
+	Session *session             `json:"session,omitempty"`
+	// Speeds are the playback speeds remembered per show (see
+	// rememberSpeed).
+	Speeds map[string]float64 `json:"speeds,omitempty"`
+	// RatedAt records when each rating was set or cleared, so the newest
+	// wins when machines share state (see syncShared).
+	RatedAt map[string]time.Time `json:"rated_at,omitempty"`
+	// AlwaysConfirm lists the kinds of action the profile no longer wants
+	// to be asked about.
+	AlwaysConfirm map[string]bool `json:"always_confirm,omitempty"`
+}
+
+type historyEntry struct {
+	Path string    `json:"path"`
+	Time time.Time `json:"time"`
+}
+
+// profileDir returns the data directory of the active profile.
+func profileDir() string {
+	return filepath.Join(dataDir(), "profiles", profileName)
+}
+
+// validProfileName rejects names that would escape the profiles directory.
+func validProfileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	st, err := parseState(data)
+	if err != nil {
+		return nil, err
+	}
+	st.path = path
+	st.loaded = data
+	// Ratings from before RatedAt was kept are dated to the last save, so
+	// they are shared too (see sharedProfileOf).
+	if info, err := os.Stat(path); err == nil {
+		for video := range st.Ratings {
+			if _, ok := st.RatedAt[video]; !ok {
+				st.RatedAt[video] = info.ModTime()
+			}
+		}
+	}
+	return st, nil
+}
+
+// parseState reads a state file's contents, or none when data is nil.
+func parseState(data []byte) (*state, error) {
+	st := &state{}
+	if data != nil {
+		if err := json.Unmarshal(data, st); err != nil {
+			return nil, err
+		}
+	}
+	if st.Ratings == nil {
+		st.Ratings = map[string]int{}
+	}
+	if st.RatedAt == nil {
+		st.RatedAt = map[string]time.Time{}
+	}
+	if st.Watched == nil {
+		st.Watched = map[string]time.Time{}
+	}
+	if st.Hidden == nil {
+		st.Hidden = map[string]bool{}
+	}
+	if st.Pinned == nil {
+		st.Pinned = map[string]bool{}
+	}
+	if st.Speeds == nil {
+		st.Speeds = map[string]float64{}
+	}
+	if st.AlwaysConfirm == nil {
+		st.AlwaysConfirm = map[string]bool{}
+	}
+	return st, nil
+}
+
+// save writes st to its file. When another instance of the profile saved
+// it since st was loaded, the changes made to st are merged into what that
+// one saved, so neither loses the other's.
+func (st *state) save() error {
+	return saveMerged(st.path, func(saved []byte) ([]byte, error) {
+		if !bytes.Equal(saved, st.loaded) {
+			if err := st.merge(saved); err != nil {
+				return nil, err
+			}
+		}
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err == nil {
+			st.loaded = data
+		}
+		return data, err
+	})
+}
+
+// merge replaces st with saved, the state another instance saved, plus the
+// changes made to st since it was loaded.
+func (st *state) merge(saved []byte) error {
+	theirs, err := parseState(saved)
+	if err != nil {
+		return err
+	}
+	base, err := parseState(st.loaded)
+	if err != nil {
+		return err
+	}
+	st.Ratings = mergeChanges(theirs.Ratings, base.Ratings, st.Ratings)
+	st.RatedAt = mergeChanges(theirs.RatedAt, base.RatedAt, st.RatedAt)
+	st.Watched = mergeChanges(theirs.Watched, base.Watched, st.Watched)
+	st.Hidden = mergeChanges(theirs.Hidden, base.Hidden, st.Hidden)
+	st.Pinned = mergeChanges(theirs.Pinned, base.Pinned, st.Pinned)
+	st.Speeds = mergeChanges(theirs.Speeds, base.Speeds, st.Speeds)
+	st.AlwaysConfirm = mergeChanges(theirs.AlwaysConfirm, base.AlwaysConfirm, st.AlwaysConfirm)
+	st.History = mergeHistory(theirs.History, base.History, st.History)
+	if sameJSON(st.Session, base.Session) {
+		st.Session = theirs.Session
+	}
+	return nil
+}
+
+// mergeHistory adds the entries played since base was loaded, those of ours
+// not in base, to saved, newest first.
+func mergeHistory(saved, base, ours []historyEntry) []historyEntry {
+	type played struct {
+		path string
+		time int64
+	}
+	seen := map[played]bool{}
+	for _, h := range base {
+		seen[played{h.Path, h.Time.UnixNano()}] = true
+	}
+	merged := slices.Clone(saved)
+	for _, h := range saved {
+		seen[played{h.Path, h.Time.UnixNano()}] = true
+	}
+	for _, h := range ours {
+		if !seen[played{h.Path, h.Time.UnixNano()}] {
+			merged = append(merged, h)
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b historyEntry) int { return b.Time.Compare(a.Time) })
+	if len(merged) > maxHistory {
+		merged = merged[:maxHistory]
+	}
+	return merged
+}
+
+// setRating stores a 1-5 star rating for path. A rating of 0 clears it.
+func (st *state) setRating(path string, stars int) {
+	st.RatedAt[path] = time.Now()
+	if stars <= 0 {
+		delete(st.Ratings, path)
+		return
+	}
+	st.Ratings[path] = min(stars, 5)
+}
+
+// addHistory records that path was just played, most recent first.
+func (st *state) addHistory(path string) {
+	st.History = append([]historyEntry{{Path: path, Time: time.Now()}}, st.History...)
+	if len(st.History) > maxHistory {
+		st.History = st.History[:maxHistory]
+	}
+}
+
+// hasData reports whether the profile rated, watched or played path.
+func (st *state) hasData(path string) bool {
+	if st.Ratings[path] > 0 || !st.Watched[path].IsZero() {
+		return true
+	}
+	for _, h := range st.History {
+		if h.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// rename moves everything recorded about oldPath over to newPath.
+func (st *state) rename(oldPath, newPath string) {
+	if stars, ok := st.Ratings[oldPath]; ok {
+		st.Ratings[newPath] = stars
+		delete(st.Ratings, oldPath)
+	}
+	if t, ok := st.RatedAt[oldPath]; ok {
+		st.RatedAt[newPath] = t
+		delete(st.RatedAt, oldPath)
+	}
+	if t, ok := st.Watched[oldPath]; ok {
+		st.Watched[newPath] = t
+		delete(st.Watched, oldPath)
+	}
+	if st.Hidden[oldPath] {
+		st.Hidden[newPath] = true
+		delete(st.Hidden, oldPath)
+	}
+	if st.Pinned[oldPath] {
+		st.Pinned[newPath] = true
+		delete(st.Pinned, oldPath)
+	}
+	for i := range st.History {
+		if st.History[i].Path == oldPath {
+			st.History[i].Path = newPath
+		}
+	}
+}
+
+// isHidden reports whether path or one of its parent folders is hidden, or
+// path is junk and the junk setting hides it.
+func (st *state) isHidden(path string) bool {
+	if hidesJunk() && isJunk(path) {
+		return true
+	}
+	for p := path; ; p = filepath.Dir(p) {
+		if st.Hidden[p] {
+			return true
+		}
+		if parent := filepath.Dir(p); parent == p {
+			return false
+		}
+	}
+}
+
+// toggleHidden hides path, or reveals it again if it is already hidden.
+func (st *state) toggleHidden(path string) bool {
+	if st.Hidden[path] {
+		delete(st.Hidden, path)
+		return false
+	}
+	st.Hidden[path] = true
+	return true
+}
+
+// isPinned reports whether path or one of its parent folders is pinned.
+func (st *state) isPinned(path string) bool {
+	for p := path; ; p = filepath.Dir(p) {
+		if st.Pinned[p] {
+			return true
+		}
+		if parent := filepath.Dir(p); parent == p {
+			return false
+		}
+	}
+}
+
+// togglePinned pins path, or unpins it if it is already pinned.
+func (st *state) togglePinned(path string) bool {
+	if st.Pinned[path] {
+		delete(st.Pinned, path)
+		return false
+	}
+	st.Pinned[path] = true
+	return true
+}
+
+// pinnedFirst moves the pinned videos to the front of videos, keeping the
+// order of the pinned videos and of the rest.
+func (st *state) pinnedFirst(videos []string) []string {
+	if len(st.Pinned) == 0 {
+		return videos
+	}
+	sorted := make([]string, 0, len(videos))
+	var rest []string
+	for _, video := range videos {
+		if st.isPinned(video) {
+			sorted = append(sorted, video)
+		} else {
+			rest = append(rest, video)
+		}
+	}
+	return append(sorted, rest...)
+}
+
+func renderStars(stars int) string {
+	if stars <= 0 {
+		return ""
+	}
+	return strings.Repeat("★", stars) + strings.Repeat("☆", 5-stars)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestStateSaveMerges saves two instances of one profile, each loaded
+// before the other saved, and checks that neither loses its changes.
+func TestStateSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Pinned["/v/pinned.mkv"] = true
+	st.setRating("/v/both.mkv", 2)
+	if err := st.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.setRating("/v/a.mkv", 5)
+	a.addHistory("/v/a.mkv")
+	delete(a.Pinned, "/v/pinned.mkv")
+	if err := a.save(); err != nil {
+		t.Fatal(err)
+	}
+	b.Hidden["/v/b.mkv"] = true
+	b.addHistory("/v/b.mkv")
+	b.setRating("/v/both.mkv", 4)
+	if err := b.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Ratings["/v/a.mkv"] != 5 || got.Ratings["/v/both.mkv"] != 4 {
+		t.Errorf("ratings = %v, want a.mkv 5 and both.mkv 4", got.Ratings)
+	}
+	if !got.Hidden["/v/b.mkv"] {
+		t.Errorf("hidden = %v, want b.mkv", got.Hidden)
+	}
+	if got.Pinned["/v/pinned.mkv"] {
+		t.Errorf("pinned = %v, want pinned.mkv unpinned", got.Pinned)
+	}
+	if len(got.History) != 2 || got.History[0].Path != "/v/b.mkv" || got.History[1].Path != "/v/a.mkv" {
+		t.Errorf("history = %v, want b.mkv then a.mkv", got.History)
+	}
+}
+
+func TestIndexSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	a, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.ensure("/v/a.mkv").Tags = []string{"a"}
+	if err := a.save(); err != nil {
+		t.Fatal(err)
+	}
+	b.ensure("/v/b.mkv").Tags = []string{"b"}
+	if err := b.save(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.tags("/v/a.mkv")) != 1 || len(got.tags("/v/b.mkv")) != 1 {
+		t.Errorf("entries = %v, want tags of a.mkv and b.mkv", got.Entries)
+	}
+}
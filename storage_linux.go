@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// isRotational reports whether path is on a spinning disk, from the block
+// device's queue/rotational flag in sysfs. known is false when the device
+// cannot be found, as for LVM, btrfs subvolumes or tmpfs.
+func isRotational(path string) (rotational, known bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return false, false
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	// A partition's queue belongs to the disk above it.
+	device, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return false, false
+	}
+	for _, dir := range []string{device, filepath.Dir(device)} {
+		data, err := os.ReadFile(filepath.Join(dir, "queue", "rotational"))
+		if err == nil {
+			return strings.TrimSpace(string(data)) == "1", true
+		}
+	}
+	return false, false
+}
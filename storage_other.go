@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// isRotational cannot tell disks apart on this platform.
+func isRotational(path string) (rotational, known bool) {
+	return false, false
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sharedState is the file named by sync_file, kept in a folder that
+// Syncthing, Dropbox or similar copies between machines. Paths are stored
+// relative to their library root so the machines may mount the library in
+// different places.
+type sharedState struct {
+	Profiles map[string]*sharedProfile `json:"profiles"`
+}
+
+type sharedProfile struct {
+	Ratings map[string]sharedRating `json:"ratings,omitempty"`
+	Watched map[string]time.Time    `json:"watched,omitempty"`
+	History []historyEntry          `json:"history,omitempty"`
+}
+
+// sharedRating is a rating and when it was given; a cleared rating is kept
+// with 0 stars so the clearing wins over older ratings from other machines.
+type sharedRating struct {
+	Stars int       `json:"stars"`
+	Time  time.Time `json:"time"`
+}
+
+// merge folds other into p: the latest rating and watch time win, and the
+// histories are combined.
+func (p *sharedProfile) merge(other *sharedProfile) {
+	if p.Ratings == nil {
+		p.Ratings = map[string]sharedRating{}
+	}
+	if p.Watched == nil {
+		p.Watched = map[string]time.Time{}
+	}
+	for path, r := range other.Ratings {
+		if r.Time.After(p.Ratings[path].Time) {
+			p.Ratings[path] = r
+		}
+	}
+	for path, t := range other.Watched {
+		if t.After(p.Watched[path]) {
+			p.Watched[path] = t
+		}
+	}
+	seen := map[historyEntry]bool{}
+	var history []historyEntry
+	for _, h := range append(p.History, other.History...) {
+		if !seen[h] {
+			seen[h] = true
+			history = append(history, h)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Time.After(history[j].Time) })
+	if len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	p.History = history
+}
+
+// syncKey is how video is named in the shared file.
+func syncKey(video string) string {
+	root := rootOf(video)
+	if root == "" {
+		return video
+	}
+	rel, err := filepath.Rel(root, video)
+	if err != nil {
+		return video
+	}
+	return filepath.ToSlash(rel)
+}
+
+// syncPath finds the local file a shared key names, trying each library
+// root. It returns "" when no root has the file.
+func syncPath(key string) string {
+	if strings.Contains(key, "://") || filepath.IsAbs(key) {
+		return key
+	}
+	for _, root := range videoDirs {
+		path := filepath.Join(root, filepath.FromSlash(key))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// sharedProfileOf describes st in the shared format.
+func sharedProfileOf(st *state) *sharedProfile {
+	p := &sharedProfile{Ratings: map[string]sharedRating{}, Watched: map[string]time.Time{}}
+	for path, t := range st.RatedAt {
+		p.Ratings[syncKey(path)] = sharedRating{Stars: st.Ratings[path], Time: t}
+	}
+	for path, t := range st.Watched {
+		p.Watched[syncKey(path)] = t
+	}
+	for _, h := range st.History {
+		p.History = append(p.History, historyEntry{Path: syncKey(h.Path), Time: h.Time})
+	}
+	return p
+}
+
+// apply copies the shared profile into st, for the files this machine has.
+func (p *sharedProfile) apply(st *state) {
+	for key, r := range p.Ratings {
+		if path := syncPath(key); path != "" && r.Time.After(st.RatedAt[path]) {
+			st.setRating(path, r.Stars)
+			st.RatedAt[path] = r.Time
+		}
+	}
+	for key, t := range p.Watched {
+		if path := syncPath(key); path != "" && t.After(st.Watched[path]) {
+			st.Watched[path] = t
+		}
+	}
+	seen := map[historyEntry]bool{}
+	for _, h := range st.History {
+		seen[h] = true
+	}
+	for _, h := range p.History {
+		if path := syncPath(h.Path); path != "" && !seen[historyEntry{path, h.Time}] {
+			st.History = append(st.History, historyEntry{Path: path, Time: h.Time})
+		}
+	}
+	sort.SliceStable(st.History, func(i, j int) bool { return st.History[i].Time.After(st.History[j].Time) })
+	if len(st.History) > maxHistory {
+		st.History = st.History[:maxHistory]
+	}
+}
+
+// conflictCopies lists the copies a sync tool leaves next to path when two
+// machines changed it at once: Syncthing's "name.sync-conflict-..." and
+// Dropbox's or Nextcloud's "name (conflicted copy ...)".
+func conflictCopies(path string) []string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	var copies []string
+	for _, pattern := range []string{stem + ".sync-conflict-*" + ext, stem + " (*conflicted copy*)" + ext} {
+		matches, _ := filepath.Glob(pattern)
+		copies = append(copies, matches...)
+	}
+	return copies
+}
+
+func readShared(path string) (*sharedState, error) {
+	shared := &sharedState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return shared, err
+	}
+	if err := json.Unmarshal(data, shared); err != nil {
+		return shared, err
+	}
+	return shared, nil
+}
+
+// syncState merges st with the shared file named by sync_file. The
+// launcher syncs when it starts and exits rather than on every save, which
+// would lock, read and rewrite the shared file at each rating.
+func syncState(st *state) {
+	if err := syncShared(st, profileName); err != nil {
+		logger.Warn("could not sync state", "path", cfg.SyncFile, "err", err)
+	}
+}
+
+// syncShared merges st, the state of profile, with the shared file and any
+// conflict copies of it, then writes the result back to both. The conflict
+// copies are removed once their changes are in the shared file. The lock
+// lives in the cache directory rather than next to the file, where the sync
+// tool would copy it around.
+func syncShared(st *state, profile string) error {
+	path, err := expandHome(cfg.SyncFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir(), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(filepath.Join(cacheDir(), "sync.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	shared, err := readShared(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if shared.Profiles == nil {
+		shared.Profiles = map[string]*sharedProfile{}
+	}
+	copies := conflictCopies(path)
+	for _, c := range copies {
+		other, err := readShared(c)
+		if err != nil {
+			logger.Warn("skipping unreadable conflict copy", "path", c, "err", err)
+			continue
+		}
+		for name, p := range other.Profiles {
+			if shared.Profiles[name] == nil {
+				shared.Profiles[name] = &sharedProfile{}
+			}
+			shared.Profiles[name].merge(p)
+		}
+	}
+	p := shared.Profiles[profile]
+	if p == nil {
+		p = &sharedProfile{}
+		shared.Profiles[profile] = p
+	}
+	p.merge(sharedProfileOf(st))
+	p.apply(st)
+
+	data, err := json.MarshalIndent(shared, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(path, data); err != nil {
+		return err
+	}
+	for _, c := range copies {
+		if err := os.Remove(c); err != nil {
+			logger.Warn("could not remove conflict copy", "path", c, "err", err)
+		}
+	}
+	logger.Debug("synced state", "path", path, "conflicts", len(copies))
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSharedProfileMerge(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	tests := []struct {
+		name        string
+		p, other    sharedProfile
+		wantRatings map[string]sharedRating
+		wantHistory []historyEntry
+	}{
+		{
+			name:        "cleared rating beats an older one",
+			p:           sharedProfile{Ratings: map[string]sharedRating{"a.mkv": {Stars: 4, Time: older}}},
+			other:       sharedProfile{Ratings: map[string]sharedRating{"a.mkv": {Stars: 0, Time: newer}}},
+			wantRatings: map[string]sharedRating{"a.mkv": {Stars: 0, Time: newer}},
+		},
+		{
+			name:        "older rating loses to a cleared one",
+			p:           sharedProfile{Ratings: map[string]sharedRating{"a.mkv": {Stars: 0, Time: newer}}},
+			other:       sharedProfile{Ratings: map[string]sharedRating{"a.mkv": {Stars: 5, Time: older}}},
+			wantRatings: map[string]sharedRating{"a.mkv": {Stars: 0, Time: newer}},
+		},
+		{
+			name:        "ratings of other files are added",
+			p:           sharedProfile{Ratings: map[string]sharedRating{"a.mkv": {Stars: 2, Time: older}}},
+			other:       sharedProfile{Ratings: map[string]sharedRating{"b.mkv": {Stars: 3, Time: older}}},
+			wantRatings: map[string]sharedRating{"a.mkv": {Stars: 2, Time: older}, "b.mkv": {Stars: 3, Time: older}},
+		},
+		{
+			name:        "history is de-duplicated, newest first",
+			p:           sharedProfile{History: []historyEntry{{"a.mkv", older}, {"b.mkv", newer}}},
+			other:       sharedProfile{History: []historyEntry{{"b.mkv", newer}, {"a.mkv", newer}}},
+			wantRatings: map[string]sharedRating{},
+			wantHistory: []historyEntry{{"b.mkv", newer}, {"a.mkv", newer}, {"a.mkv", older}},
+		},
+	}
+	for _, tt := range tests {
+		tt.p.merge(&tt.other)
+		if len(tt.p.Ratings) != len(tt.wantRatings) {
+			t.Errorf("%s: ratings = %v, want %v", tt.name, tt.p.Ratings, tt.wantRatings)
+		}
+		for path, want := range tt.wantRatings {
+			if got := tt.p.Ratings[path]; got.Stars != want.Stars || !got.Time.Equal(want.Time) {
+				t.Errorf("%s: rating of %s = %v, want %v", tt.name, path, got, want)
+			}
+		}
+		if len(tt.p.History) != len(tt.wantHistory) {
+			t.Fatalf("%s: history = %v, want %v", tt.name, tt.p.History, tt.wantHistory)
+		}
+		for i, want := range tt.wantHistory {
+			if got := tt.p.History[i]; got.Path != want.Path || !got.Time.Equal(want.Time) {
+				t.Errorf("%s: history[%d] = %v, want %v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestSharedProfileApply(t *testing.T) {
+	root := t.TempDir()
+	defer func(dirs []string) { videoDirs = dirs }(videoDirs)
+	videoDirs = []string{root}
+	for _, name := range []string{"a.mkv", "b.mkv"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(root, "a.mkv"), filepath.Join(root, "b.mkv")
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	st := &state{
+		Ratings: map[string]int{a: 4, b: 5},
+		RatedAt: map[string]time.Time{a: older, b: newer},
+		Watched: map[string]time.Time{},
+		History: []historyEntry{{a, older}},
+	}
+	p := &sharedProfile{
+		Ratings: map[string]sharedRating{"a.mkv": {Stars: 0, Time: newer}, "b.mkv": {Stars: 1, Time: older}, "gone.mkv": {Stars: 3, Time: newer}},
+		Watched: map[string]time.Time{"b.mkv": newer},
+		History: []historyEntry{{"a.mkv", older}, {"b.mkv", newer}, {"gone.mkv", newer}},
+	}
+	p.apply(st)
+
+	if stars, ok := st.Ratings[a]; ok {
+		t.Errorf("rating of a = %d, want it cleared by the newer shared one", stars)
+	}
+	if st.Ratings[b] != 5 {
+		t.Errorf("rating of b = %d, want 5 kept over the older shared one", st.Ratings[b])
+	}
+	if !st.Watched[b].Equal(newer) {
+		t.Errorf("watched b = %v, want %v", st.Watched[b], newer)
+	}
+	want := []historyEntry{{b, newer}, {a, older}}
+	if len(st.History) != len(want) {
+		t.Fatalf("history = %v, want %v", st.History, want)
+	}
+	for i := range want {
+		if st.History[i].Path != want[i].Path || !st.History[i].Time.Equal(want[i].Time) {
+			t.Errorf("history[%d] = %v, want %v", i, st.History[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "os/exec"
+
+// syncplayConfig is the [syncplay] table of config.toml, used to watch a
+// video in sync with friends through a Syncplay server.
+type syncplayConfig struct {
+	// Command is the Syncplay executable (default "syncplay").
+	Command string `toml:"command"`
+	Server  string `toml:"server"`
+	Room    string `toml:"room"`
+	Name    string `toml:"name"`
+	// Args are passed on to the player.
+	Args []string `toml:"args"`
+}
+
+// syncplayCommand returns the Syncplay invocation that opens video in the
+// configured room, using VIDEO_PLAYER as Syncplay's player.
+func syncplayCommand(video string) (string, []string) {
+	sp := cfg.Syncplay
+	command := sp.Command
+	if command == "" {
+		command = "syncplay"
+	}
+	args := []string{"--no-gui"}
+	if sp.Server != "" {
+		args = append(args, "-a", sp.Server)
+	}
+	if sp.Room != "" {
+		args = append(args, "-r", sp.Room)
+	}
+	if sp.Name != "" {
+		args = append(args, "-n", sp.Name)
+	}
+	player := videoPlayer
+	if path, err := exec.LookPath(player); err == nil {
+		player = path
+	}
+	args = append(args, "--player-path", player, video)
+	if len(sp.Args) > 0 {
+		args = append(append(args, "--"), sp.Args...)
+	}
+	return command, args
+}
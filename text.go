@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// truncateMiddle shortens s to at most width terminal cells by replacing its
+// middle with an ellipsis, so both the start of a path and its file name stay
+// visible. Widths are measured per grapheme cluster, which keeps wide CJK
+// characters and emoji intact and correctly sized.
+func truncateMiddle(s string, width int) string {
+	if width <= 0 || uniseg.StringWidth(s) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	var clusters []string
+	var widths []int
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		clusters = append(clusters, g.Str())
+		widths = append(widths, g.Width())
+	}
+
+	headWidth := (width - 1) / 2
+	tailWidth := width - 1 - headWidth
+	var head, tail []string
+	used := 0
+	for i := 0; i < len(clusters) && used+widths[i] <= headWidth; i++ {
+		head = append(head, clusters[i])
+		used += widths[i]
+	}
+	tailWidth += headWidth - used
+	used = 0
+	for i := len(clusters) - 1; i >= len(head) && used+widths[i] <= tailWidth; i-- {
+		tail = append([]string{clusters[i]}, tail...)
+		used += widths[i]
+	}
+	return strings.Join(head, "") + "…" + strings.Join(tail, "")
+}
+
+// naturalCompare compares a and b the way people sort names: runs of digits
+// by their value, so "Episode 2" comes before "Episode 10", and letters
+// regardless of case. Names that differ only in case or leading zeros are
+// ordered by their bytes, so no two different names compare equal.
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			startA, startB := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			numA := strings.TrimLeft(a[startA:i], "0")
+			numB := strings.TrimLeft(b[startB:j], "0")
+			// Without leading zeros the longer number is the larger.
+			if c := cmp.Or(cmp.Compare(len(numA), len(numB)), strings.Compare(numA, numB)); c != 0 {
+				return c
+			}
+			continue
+		}
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if c := cmp.Compare(unicode.ToLower(ra), unicode.ToLower(rb)); c != 0 {
+			return c
+		}
+		i += sizeA
+		j += sizeB
+	}
+	return cmp.Or(cmp.Compare(len(a)-i, len(b)-j), strings.Compare(a, b))
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// fuzzyScore reports whether the letters of query appear in s in order,
+// ignoring case, so "tgcol" finds "Toggle the column view". Matches score
+// higher the more letters start words or follow the previous match.
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	score, last := 0, -2
+	prev := ' '
+	for i, r := range []rune(strings.ToLower(s)) {
+		if len(q) == 0 {
+			break
+		}
+		if r == q[0] {
+			score++
+			if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 2
+			}
+			if last == i-1 {
+				score += 3
+			}
+			last = i
+			q = q[1:]
+		}
+		prev = r
+	}
+	return score, len(q) == 0
+}
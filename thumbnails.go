@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sheetTiles is the number of thumbnails per row and column of a sheet.
+const sheetTiles = 4
+
+// sheetPath is where the thumbnail sheet of video is cached.
+func sheetPath(video string) string {
+	return filepath.Join(cacheDir(), "thumbnails", watchLaterName(video)+".jpg")
+}
+
+// thumbnailSheet returns the contact sheet of video, generating it with
+// ffmpeg unless a sheet newer than the video is cached. Only keyframes are
+// decoded, so it takes seconds even for a feature film. Cancelling ctx
+// stops ffmpeg.
+func thumbnailSheet(ctx context.Context, video string) (string, error) {
+	info, err := os.Stat(video)
+	if err != nil {
+		return "", err
+	}
+	path := sheetPath(video)
+	if sheet, err := os.Stat(path); err == nil && !sheet.ModTime().Before(info.ModTime()) {
+		return path, nil
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", errors.New("thumbnail sheets need ffmpeg")
+	}
+	duration, err := videoDuration(ctx, video)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	filter := fmt.Sprintf("fps=%d/%f,scale=320:-2,tile=%dx%d", sheetTiles*sheetTiles, duration, sheetTiles, sheetTiles)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-nostdin", "-y", "-hide_banner", "-loglevel", "error",
+		"-skip_frame", "nokey", "-i", video, "-vf", filter, "-frames:v", "1", "-q:v", "4", path)
+	logger.Info("generating thumbnail sheet", "cmd", cmd.Args)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		// Don't leave a half-written sheet to be taken for a finished one.
+		os.Remove(path)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = errors.New(lastLine(msg))
+		}
+		return "", fmt.Errorf("ffmpeg: %w", err)
+	}
+	return path, nil
+}
+
+// videoDuration asks ffprobe for the length of video in seconds.
+func videoDuration(ctx context.Context, video string) (float64, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", video).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %w", err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("ffprobe: no duration for %s", filepath.Base(video))
+	}
+	return duration, nil
+}
+
+type sheetMsg struct {
+	video string
+	img   image.Image
+	err   error
+}
+
+// loadSheet generates or reads the thumbnail sheet of video in the
+// background.
+func loadSheet(ctx context.Context, video string) tea.Cmd {
+	return func() tea.Msg {
+		path, err := thumbnailSheet(ctx, video)
+		if err != nil {
+			return sheetMsg{video: video, err: err}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return sheetMsg{video: video, err: err}
+		}
+		defer f.Close()
+		img, err := jpeg.Decode(f)
+		return sheetMsg{video: video, img: img, err: err}
+	}
+}
+
+func (m model) updateSheet(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch keys().command("thumbnails", msg.String()) {
+	case "play":
+		m.sheetMode = false
+		m.leaveView()
+		return m.play(m.sheetVideo)
+	case "close":
+		m.sheetMode = false
+		m.leaveView()
+	}
+	return m, nil
+}
+
+func (m model) viewSheet() string {
+	s := truncateMiddle(m.label(m.sheetVideo), m.width) + "\n"
+	s += keys().help("Thumbnails - %s to play, %s to close", "thumbnails", "play", "close") + "\n\n"
+	if m.sheet == nil {
+		return s + tr("generating thumbnails...") + "\n"
+	}
+	return s + renderImage(m.sheet, m.width, m.height-4)
+}
+
+// renderImage draws img within cols×rows terminal cells using "▀" half
+// blocks in 24-bit colour, two pixels per cell, keeping its aspect ratio.
+func renderImage(img image.Image, cols, rows int) string {
+	b := img.Bounds()
+	if cols <= 0 {
+		cols = 80
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+	scale := math.Min(float64(cols)/float64(b.Dx()), float64(2*rows)/float64(b.Dy()))
+	w, h := max(int(float64(b.Dx())*scale), 1), max(int(float64(b.Dy())*scale)/2*2, 2)
+	// pixel averages the source pixels covered by output pixel (x, y).
+	pixel := func(x, y int) (uint32, uint32, uint32) {
+		x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		var r, g, bl, n uint32
+		for sy := y0; sy < max(y1, y0+1); sy++ {
+			for sx := x0; sx < max(x1, x0+1); sx++ {
+				pr, pg, pb, _ := img.At(sx, sy).RGBA()
+				r, g, bl, n = r+pr>>8, g+pg>>8, bl+pb>>8, n+1
+			}
+		}
+		return r / n, g / n, bl / n
+	}
+	var sb strings.Builder
+	for y := 0; y < h; y += 2 {
+		for x := 0; x < w; x++ {
+			tr, tg, tb := pixel(x, y)
+			br, bg, bb := pixel(x, y+1)
+			fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr, tg, tb, br, bg, bb)
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+	return sb.String()
+}
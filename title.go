@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// releaseTag matches the scene/release markers that usually follow the
+	// title in a file name; everything from the first one on is dropped.
+	releaseTag = regexp.MustCompile(`(?i)\b(480p|576p|720p|1080[pi]|2160p|4k|uhd|hdr|hdr10|dv|bluray|blu ray|bdrip|brrip|dvdrip|dvdscr|webrip|web dl|webdl|web|hdtv|remux|x264|x265|h264|h265|hevc|avc|xvid|divx|aac|ac3|dts|ddp?5 1|atmos|truehd|10bit|proper|repack|extended|unrated|internal|limited|multi|subbed|dubbed)\b`)
+	bracketed  = regexp.MustCompile(`\[[^\]]*\]|\{[^}]*\}`)
+	yearRe     = regexp.MustCompile(`\(?\b(19\d{2}|20\d{2})\b\)?`)
+)
+
+// cleanTitle derives a human readable title and release year from a file
+// name, e.g. "Blade.Runner.2049.2017.2160p.UHD.mkv" -> "Blade Runner 2049", 2017.
+// Episode markers such as S01E02 are kept. The year is 0 when none is found.
+func cleanTitle(path string) (string, int) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name = bracketed.ReplaceAllString(name, " ")
+	name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+	if loc := releaseTag.FindStringIndex(name); loc != nil && loc[0] > 0 {
+		name = name[:loc[0]]
+	}
+
+	// The last year-like number is the release year, so titles that contain
+	// a year ("Blade Runner 2049") keep it.
+	year := 0
+	if locs := yearRe.FindAllStringSubmatchIndex(name, -1); len(locs) > 0 {
+		loc := locs[len(locs)-1]
+		if loc[0] > 0 {
+			year, _ = strconv.Atoi(name[loc[2]:loc[3]])
+			name = name[:loc[0]]
+		}
+	}
+	title := strings.Join(strings.Fields(strings.Trim(name, " -()")), " ")
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return title, year
+}
+
+// videoTitle returns the scraped title of video when known, otherwise the
+// one cleaned from its file name, with the year appended when known.
+func videoTitle(video string, ix *index) string {
+	title, year := titleYear(video, ix)
+	if year > 0 {
+		return fmt.Sprintf("%s (%d)", title, year)
+	}
+	return title
+}
+
+// titleYear returns the title and year of video set in a folder's
+// .movie-launcher.toml, scraped, or cleaned from its file name. An
+// overridden title keeps the episode number of episodes.
+func titleYear(video string, ix *index) (string, int) {
+	if o := overrideFor(video); o.Title != "" {
+		if ep, ok := parseEpisode(video); ok {
+			return fmt.Sprintf("%s S%02dE%02d", o.Title, ep.season, ep.episode), o.Year
+		}
+		return o.Title, o.Year
+	}
+	if md := ix.metadata(video); md != nil && md.Title != "" {
+		return md.Title, md.Year
+	}
+	return cleanTitle(video)
+}
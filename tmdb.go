@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tmdbAPI = "https://api.themoviedb.org/3"
+
+var tmdbClient = &http.Client{Timeout: 10 * time.Second}
+
+// tmdbConfig is the [tmdb] table. An API key can be requested for free at
+// https://www.themoviedb.org/settings/api.
+// Region picks the country whose age ratings are scraped, "US" by default.
+type tmdbConfig struct {
+	APIKey   string `toml:"api_key"`
+	Language string `toml:"language"`
+	Region   string `toml:"region"`
+}
+
+// tmdbGet fetches path from the TMDB API into out.
+func tmdbGet(ctx context.Context, path string, params url.Values, out any) error {
+	if cfg.TMDB.APIKey == "" {
+		return errors.New("set api_key under [tmdb] in config.toml")
+	}
+	params.Set("api_key", cfg.TMDB.APIKey)
+	if cfg.TMDB.Language != "" {
+		params.Set("language", cfg.TMDB.Language)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", tmdbAPI+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := tmdbClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TMDB: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// tmdbMovie is a search result of the TMDB API.
+type tmdbMovie struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	ReleaseDate string  `json:"release_date"`
+	Overview    string  `json:"overview"`
+	VoteAverage float64 `json:"vote_average"`
+}
+
+// tmdbSearch finds the film called title, released in year if that is
+// known.
+func tmdbSearch(ctx context.Context, title string, year int) (tmdbMovie, error) {
+	params := url.Values{"query": {title}}
+	if year > 0 {
+		params.Set("year", strconv.Itoa(year))
+	}
+	var resp struct {
+		Results []tmdbMovie `json:"results"`
+	}
+	if err := tmdbGet(ctx, "/search/movie", params, &resp); err != nil {
+		return tmdbMovie{}, err
+	}
+	if len(resp.Results) == 0 {
+		return tmdbMovie{}, fmt.Errorf("%s not found on TMDB", title)
+	}
+	return resp.Results[0], nil
+}
+
+// tmdbMetadata scrapes the title, year, plot, rating and certification of
+// video from TMDB, searching for the title cleaned from its file name.
+// Episodes are looked up in their show's guide instead (see
+// tmdbEpisodeMetadata).
+func tmdbMetadata(ctx context.Context, video string) (metadata, error) {
+	if ep, ok := parseEpisode(video); ok {
+		return tmdbEpisodeMetadata(ctx, ep)
+	}
+	title, year := cleanTitle(video)
+	movie, err := tmdbSearch(ctx, title, year)
+	if err != nil {
+		return metadata{}, err
+	}
+	md := metadata{Title: movie.Title, Plot: movie.Overview, Rating: movie.VoteAverage}
+	if len(movie.ReleaseDate) >= 4 {
+		md.Year, _ = strconv.Atoi(movie.ReleaseDate[:4])
+	}
+	if md.Certification, err = tmdbCertification(ctx, movie.ID); err != nil {
+		logger.Warn("TMDB certification lookup failed", "title", movie.Title, "err", err)
+	}
+	return md, nil
+}
+
+// tmdbCertification returns the age rating of a film in the configured
+// region, preferring that of its theatrical release.
+func tmdbCertification(ctx context.Context, id int) (string, error) {
+	region := cfg.TMDB.Region
+	if region == "" {
+		region = "US"
+	}
+	var resp struct {
+		Results []struct {
+			Country  string `json:"iso_3166_1"`
+			Releases []struct {
+				Certification string `json:"certification"`
+				Type          int    `json:"type"`
+			} `json:"release_dates"`
+		} `json:"results"`
+	}
+	if err := tmdbGet(ctx, fmt.Sprintf("/movie/%d/release_dates", id), url.Values{}, &resp); err != nil {
+		return "", err
+	}
+	var found string
+	for _, r := range resp.Results {
+		if !strings.EqualFold(r.Country, region) {
+			continue
+		}
+		for _, release := range r.Releases {
+			// Type 3 is the theatrical release.
+			if release.Certification != "" && (found == "" || release.Type == 3) {
+				found = release.Certification
+			}
+		}
+	}
+	return found, nil
+}
+
+// tmdbEpisodeMetadata scrapes the title, plot, rating, runtime and air date
+// of the episode ep from its show's guide on TMDB, finding the show by
+// name. An absolute-numbered episode is first found among the seasons.
+func tmdbEpisodeMetadata(ctx context.Context, ep episode) (metadata, error) {
+	var shows struct {
+		Results []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"results"`
+	}
+	if err := tmdbGet(ctx, "/search/tv", url.Values{"query": {ep.show}}, &shows); err != nil {
+		return metadata{}, err
+	}
+	if len(shows.Results) == 0 {
+		return metadata{}, fmt.Errorf("%s not found on TMDB", ep.show)
+	}
+	show := shows.Results[0]
+	if ep.absolute > 0 {
+		var err error
+		if ep.season, ep.episode, err = tmdbAbsolute(ctx, show.ID, ep.absolute); err != nil {
+			return metadata{}, err
+		}
+	}
+	var found struct {
+		Name        string  `json:"name"`
+		Overview    string  `json:"overview"`
+		AirDate     string  `json:"air_date"`
+		Runtime     int     `json:"runtime"`
+		VoteAverage float64 `json:"vote_average"`
+		Season      int     `json:"season_number"`
+		Episode     int     `json:"episode_number"`
+	}
+	path := fmt.Sprintf("/tv/%d/season/%d/episode/%d", show.ID, ep.season, ep.episode)
+	if err := tmdbGet(ctx, path, url.Values{}, &found); err != nil {
+		return metadata{}, err
+	}
+	md := metadata{
+		Title:   fmt.Sprintf("%s S%02dE%02d", show.Name, found.Season, found.Episode),
+		Plot:    found.Overview,
+		Runtime: found.Runtime,
+		Rating:  found.VoteAverage,
+		Season:  found.Season,
+		Episode: found.Episode,
+		Aired:   found.AirDate,
+	}
+	if found.Name != "" {
+		md.Title += " - " + found.Name
+	}
+	if len(found.AirDate) >= 4 {
+		md.Year, _ = strconv.Atoi(found.AirDate[:4])
+	}
+	return md, nil
+}
+
+// tmdbAbsolute finds the season and episode of the show id that is its nth
+// episode, counting those of its seasons in order and leaving out the
+// specials.
+func tmdbAbsolute(ctx context.Context, id, n int) (int, int, error) {
+	type season struct {
+		Number   int `json:"season_number"`
+		Episodes int `json:"episode_count"`
+	}
+	var show struct {
+		Name    string   `json:"name"`
+		Seasons []season `json:"seasons"`
+	}
+	if err := tmdbGet(ctx, fmt.Sprintf("/tv/%d", id), url.Values{}, &show); err != nil {
+		return 0, 0, err
+	}
+	slices.SortFunc(show.Seasons, func(a, b season) int { return a.Number - b.Number })
+	left := n
+	for _, s := range show.Seasons {
+		if s.Number == 0 {
+			continue
+		}
+		if left <= s.Episodes {
+			return s.Number, left, nil
+		}
+		left -= s.Episodes
+	}
+	return 0, 0, fmt.Errorf("%s has no episode %d on TMDB", show.Name, n)
+}
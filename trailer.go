@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trailerMsg carries the player command for a trailer, or why none was
+// found.
+type trailerMsg struct {
+	title string
+	cmd   *exec.Cmd
+	err   error
+}
+
+type trailerDoneMsg struct{ err error }
+
+// trailerURL looks up the YouTube trailer of the film called title on
+// TMDB, preferring official trailers over teasers and clips.
+func trailerURL(ctx context.Context, title string, year int) (string, error) {
+	movie, err := tmdbSearch(ctx, title, year)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Results []struct {
+			Key      string `json:"key"`
+			Site     string `json:"site"`
+			Type     string `json:"type"`
+			Official bool   `json:"official"`
+		} `json:"results"`
+	}
+	if err := tmdbGet(ctx, fmt.Sprintf("/movie/%d/videos", movie.ID), url.Values{}, &resp); err != nil {
+		return "", err
+	}
+	best, bestScore := "", 0
+	for _, v := range resp.Results {
+		if v.Site != "YouTube" {
+			continue
+		}
+		score := 1
+		if v.Type == "Trailer" {
+			score += 2
+		}
+		if v.Official {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = v.Key, score
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no trailer for %s on TMDB", title)
+	}
+	return "https://www.youtube.com/watch?v=" + best, nil
+}
+
+// findTrailer looks up the trailer of video in the background and prepares
+// the player for it. mpv streams YouTube through yt-dlp itself; other
+// players get the stream address resolved by yt-dlp. Cancelling ctx stops
+// the lookup.
+func findTrailer(ctx context.Context, video string, ix *index) tea.Cmd {
+	title, year := titleYear(video, ix)
+	return func() tea.Msg {
+		link, err := trailerURL(ctx, title, year)
+		if err != nil {
+			return trailerMsg{title: title, err: err}
+		}
+		if _, err := exec.LookPath("yt-dlp"); err != nil {
+			return trailerMsg{title: title, err: errors.New("playing trailers needs yt-dlp")}
+		}
+		if !isMpv(videoPlayer) {
+			out, err := exec.CommandContext(ctx, "yt-dlp", "-f", "best", "-g", link).Output()
+			if err != nil {
+				return trailerMsg{title: title, err: fmt.Errorf("yt-dlp: %w", err)}
+			}
+			link = strings.TrimSpace(lastLine(strings.TrimSpace(string(out))))
+		}
+		logger.Info("playing trailer", "title", title, "url", link)
+		return trailerMsg{title: title, cmd: exec.Command(videoPlayer, link)}
+	}
+}
+
+// playTrailer runs the trailer player in the foreground, giving the
+// terminal back to the list when it exits. Trailers are not recorded in the
+// watch history.
+func playTrailer(msg trailerMsg) tea.Cmd {
+	return tea.ExecProcess(msg.cmd, func(err error) tea.Msg { return trailerDoneMsg{err} })
+}
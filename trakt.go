@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const traktAPI = "https://api.trakt.tv"
+
+// traktConfig is the [trakt] table: the client id of a Trakt API app and
+// an OAuth access token for the account.
+type traktConfig struct {
+	ClientID    string `toml:"client_id"`
+	AccessToken string `toml:"access_token"`
+}
+
+func (c traktConfig) request(ctx context.Context, method, path string, body io.Reader, out any) error {
+	if c.ClientID == "" || c.AccessToken == "" {
+		return fmt.Errorf("set client_id and access_token under [trakt] in config.toml")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, traktAPI+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", c.ClientID)
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	return fetchJSON(req, out)
+}
+
+type traktMovie struct {
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+}
+
+// traktRecords lists the watched films and episodes and the playback
+// progress of the Trakt account.
+func traktRecords(ctx context.Context, c traktConfig) ([]watchRecord, error) {
+	var records []watchRecord
+
+	var movies []struct {
+		LastWatchedAt time.Time  `json:"last_watched_at"`
+		Movie         traktMovie `json:"movie"`
+	}
+	if err := c.request(ctx, "GET", "/sync/watched/movies", nil, &movies); err != nil {
+		return nil, err
+	}
+	for _, m := range movies {
+		records = append(records, watchRecord{title: m.Movie.Title, year: m.Movie.Year, watched: m.LastWatchedAt})
+	}
+
+	var shows []struct {
+		Show    traktMovie `json:"show"`
+		Seasons []struct {
+			Number   int `json:"number"`
+			Episodes []struct {
+				Number        int       `json:"number"`
+				LastWatchedAt time.Time `json:"last_watched_at"`
+			} `json:"episodes"`
+		} `json:"seasons"`
+	}
+	if err := c.request(ctx, "GET", "/sync/watched/shows", nil, &shows); err != nil {
+		return nil, err
+	}
+	for _, s := range shows {
+		for _, season := range s.Seasons {
+			for _, ep := range season.Episodes {
+				records = append(records, watchRecord{show: s.Show.Title, season: season.Number, episode: ep.Number, watched: ep.LastWatchedAt})
+			}
+		}
+	}
+
+	var playback []struct {
+		Progress float64    `json:"progress"`
+		Type     string     `json:"type"`
+		Movie    traktMovie `json:"movie"`
+		Show     traktMovie `json:"show"`
+		Episode  struct {
+			Season int `json:"season"`
+			Number int `json:"number"`
+		} `json:"episode"`
+	}
+	if err := c.request(ctx, "GET", "/sync/playback", nil, &playback); err != nil {
+		return nil, err
+	}
+	for _, p := range playback {
+		r := watchRecord{title: p.Movie.Title, year: p.Movie.Year, progress: p.Progress}
+		if p.Type == "episode" {
+			r = watchRecord{show: p.Show.Title, season: p.Episode.Season, episode: p.Episode.Number, progress: p.Progress}
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// traktAdded counts what Trakt accepted from an export.
+type traktAdded struct {
+	watched, ratings int
+}
+
+// traktExport adds the watched items to the account's history and sends
+// the star ratings, doubled to Trakt's scale of 10.
+func traktExport(ctx context.Context, c traktConfig, items []exportItem) (traktAdded, error) {
+	type ids struct {
+		Title string `json:"title,omitempty"`
+		Year  int    `json:"year,omitempty"`
+	}
+	type movie struct {
+		ids
+		WatchedAt *time.Time `json:"watched_at,omitempty"`
+		Rating    int        `json:"rating,omitempty"`
+	}
+	type episode struct {
+		Number    int        `json:"number"`
+		WatchedAt *time.Time `json:"watched_at,omitempty"`
+		Rating    int        `json:"rating,omitempty"`
+	}
+	type season struct {
+		Number   int       `json:"number"`
+		Episodes []episode `json:"episodes"`
+	}
+	type show struct {
+		ids
+		Seasons []season `json:"seasons"`
+	}
+	type payload struct {
+		Movies []movie `json:"movies"`
+		Shows  []show  `json:"shows"`
+	}
+	build := func(include func(exportItem) bool, set func(exportItem) (*time.Time, int)) payload {
+		var p payload
+		shows := map[string]*show{}
+		for _, item := range items {
+			if !include(item) {
+				continue
+			}
+			at, rating := set(item)
+			if item.Show == "" {
+				p.Movies = append(p.Movies, movie{ids{item.Title, item.Year}, at, rating})
+				continue
+			}
+			s := shows[item.Show]
+			if s == nil {
+				s = &show{ids: ids{Title: item.Show}}
+				shows[item.Show] = s
+			}
+			ep := episode{item.Episode, at, rating}
+			if n := len(s.Seasons); n > 0 && s.Seasons[n-1].Number == item.Season {
+				s.Seasons[n-1].Episodes = append(s.Seasons[n-1].Episodes, ep)
+			} else {
+				s.Seasons = append(s.Seasons, season{item.Season, []episode{ep}})
+			}
+		}
+		for _, s := range shows {
+			p.Shows = append(p.Shows, *s)
+		}
+		return p
+	}
+
+	var added traktAdded
+	var resp struct {
+		Added struct {
+			Movies   int `json:"movies"`
+			Episodes int `json:"episodes"`
+		} `json:"added"`
+	}
+	history := build(func(i exportItem) bool { return !i.Watched.IsZero() }, func(i exportItem) (*time.Time, int) {
+		return &i.Watched, 0
+	})
+	if err := c.post(ctx, "/sync/history", history, &resp); err != nil {
+		return added, err
+	}
+	added.watched = resp.Added.Movies + resp.Added.Episodes
+	ratings := build(func(i exportItem) bool { return i.Stars > 0 }, func(i exportItem) (*time.Time, int) {
+		return nil, i.Stars * 2
+	})
+	if err := c.post(ctx, "/sync/ratings", ratings, &resp); err != nil {
+		return added, err
+	}
+	added.ratings = resp.Added.Movies + resp.Added.Episodes
+	return added, nil
+}
+
+func (c traktConfig) post(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.request(ctx, "POST", path, bytes.NewReader(data), out)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// transcodePreset is a [[transcode]] table: ffmpeg output arguments and the
+// container to remux or transcode a video into before copying it to a
+// tablet or casting it to a picky TV.
+type transcodePreset struct {
+	Name string   `toml:"name"`
+	Args []string `toml:"args"`
+	// Ext is the container of the output, e.g. "mp4".
+	Ext string `toml:"ext"`
+	// Dir is where the output is written, defaulting to transcode_dir.
+	Dir string `toml:"dir"`
+}
+
+// defaultPresets are offered when config.toml defines no [[transcode]].
+var defaultPresets = []transcodePreset{
+	{Name: "remux", Ext: "mp4", Args: []string{"-c", "copy", "-movflags", "+faststart"}},
+	{Name: "h264", Ext: "mp4", Args: []string{"-c:v", "libx264", "-preset", "medium", "-crf", "22",
+		"-c:a", "aac", "-b:a", "160k", "-movflags", "+faststart"}},
+}
+
+// transcodeActions lists the presets for the action menu when ffmpeg is
+// installed.
+func transcodeActions() []listAction {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil
+	}
+	presets := cfg.Transcode
+	if len(presets) == 0 {
+		presets = defaultPresets
+	}
+	var actions []listAction
+	for _, p := range presets {
+		description := fmt.Sprintf("convert to .%s with ffmpeg", p.ext())
+		actions = append(actions, listAction{"transcode", pluginAction{Name: p.Name, Description: description}, p.run})
+	}
+	return actions
+}
+
+func (p transcodePreset) ext() string {
+	if ext := strings.TrimPrefix(p.Ext, "."); ext != "" {
+		return ext
+	}
+	return "mkv"
+}
+
+// output returns the file the preset writes for video.
+func (p transcodePreset) output(video string) (string, error) {
+	dir := p.Dir
+	if dir == "" {
+		dir = cfg.TranscodeDir
+	}
+	if dir == "" {
+		return "", errors.New("set transcode_dir in config.toml to transcode")
+	}
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, rest)
+	}
+	name := strings.TrimSuffix(filepath.Base(video), filepath.Ext(video))
+	return filepath.Join(dir, name+"."+p.ext()), nil
+}
+
+// run converts video with ffmpeg. An existing output is never overwritten.
+func (p transcodePreset) run(video string) (string, error) {
+	out, err := p.output(video)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return "", err
+	}
+	args := append([]string{"-nostdin", "-n", "-hide_banner", "-loglevel", "error", "-i", video}, p.Args...)
+	cmd := exec.Command("ffmpeg", append(args, out)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	logger.Info("transcoding", "cmd", cmd.Args)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(lastLine(msg))
+		}
+		return "", fmt.Errorf("%s: %w", p.Name, err)
+	}
+	return "Wrote " + out, nil
+}
+
+// lastLine returns the last line of s, where tools usually put the error.
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// treeEntry is a row of the folder view: a folder, with the number of
+// listed videos below it, or a video.
+type treeEntry struct {
+	path  string
+	dir   bool
+	count int
+}
+
+var crumbStyle = lipgloss.NewStyle().Bold(true)
+
+// treeEntries lists the folders and videos directly in dir, of the videos
+// in the list (so the filter, sort, pins and hidden videos apply). Folders
+// come first, pinned ones leading and then in natural order by name (see
+// naturalCompare), then videos in the order of the list. The top, "", holds
+// the library roots.
+func (m model) treeEntries(dir string) []treeEntry {
+	var folders, files []treeEntry
+	seen := map[string]int{}
+	for _, video := range m.videos {
+		var child string
+		switch {
+		case dir == "":
+			child = rootOf(video)
+			if child == "" {
+				continue
+			}
+		case !within(dir, video):
+			continue
+		default:
+			rel, err := filepath.Rel(dir, video)
+			if err != nil {
+				continue
+			}
+			first, _, nested := strings.Cut(rel, string(filepath.Separator))
+			if !nested {
+				files = append(files, treeEntry{path: video})
+				continue
+			}
+			child = filepath.Join(dir, first)
+		}
+		if i, ok := seen[child]; ok {
+			folders[i].count++
+			continue
+		}
+		seen[child] = len(folders)
+		folders = append(folders, treeEntry{path: child, dir: true, count: 1})
+	}
+	slices.SortStableFunc(folders, func(a, b treeEntry) int {
+		if pa, pb := m.state.isPinned(a.path), m.state.isPinned(b.path); pa != pb {
+			if pa {
+				return -1
+			}
+			return 1
+		}
+		return naturalCompare(filepath.Base(a.path), filepath.Base(b.path))
+	})
+	return append(folders, files...)
+}
+
+// treeTop is the folder the folder view starts from: the only library
+// root, or the list of roots when there are several.
+func treeTop() string {
+	if len(videoDirs) == 1 {
+		return videoDirs[0]
+	}
+	return ""
+}
+
+// crumbs are the folders from the top of the folder view down to dir, the
+// breadcrumb shown above it.
+func crumbs(dir string) []string {
+	var path []string
+	for d := dir; d != ""; d = filepath.Dir(d) {
+		path = append([]string{d}, path...)
+		if d == treeTop() || d == rootOf(d) {
+			break
+		}
+	}
+	if treeTop() == "" {
+		path = append([]string{""}, path...)
+	}
+	return path
+}
+
+// crumbName is how the folder dir is named in the breadcrumb.
+func crumbName(dir string) string {
+	if dir == "" {
+		return tr("All roots")
+	}
+	return filepath.Base(dir)
+}
+
+// openTree shows the folder holding the selected video, with it selected.
+// The folder view has a filter and sort order of its own.
+func (m *model) openTree() {
+	var video string
+	if m.cursor < len(m.videos) {
+		video = m.videos[m.cursor]
+	}
+	m.treeMode = true
+	m.crumbMode = false
+	m.treeDir = treeTop()
+	m.treeCursor = 0
+	m.swapViewSettings()
+	if rootOf(video) == "" {
+		return
+	}
+	m.treeDir = filepath.Dir(video)
+	m.selectInTree(video)
+}
+
+// enterTree shows the folder dir, selecting from, the folder or video it
+// was reached from, if it is listed there.
+func (m *model) enterTree(dir, from string) {
+	m.treeDir = dir
+	m.treeCursor = 0
+	m.selectInTree(from)
+}
+
+func (m *model) selectInTree(path string) {
+	for i, e := range m.treeEntries(m.treeDir) {
+		if e.path == path {
+			m.treeCursor = i
+			return
+		}
+	}
+}
+
+// closeTree goes back to the list, with its own filter and sort order,
+// moving its cursor to the video selected in the folder view.
+func (m *model) closeTree() {
+	var selected string
+	if entries := m.treeEntries(m.treeDir); m.treeCursor < len(entries) && !entries[m.treeCursor].dir {
+		selected = entries[m.treeCursor].path
+	}
+	m.treeMode = false
+	m.crumbMode = false
+	m.swapViewSettings()
+	if selected == "" {
+		return
+	}
+	for i, video := range m.videos {
+		if video == selected {
+			m.cursor = i
+			m.clampViewport()
+			return
+		}
+	}
+}
+
+func (m model) updateTree(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.crumbMode {
+		return m.updateCrumbs(msg)
+	}
+	entries := m.treeEntries(m.treeDir)
+	m.status = ""
+	command := keys().command("folders", msg.String())
+	switch command {
+	case "up":
+		m.treeCursor = max(m.treeCursor-1, 0)
+	case "down":
+		m.treeCursor = max(min(m.treeCursor+1, len(entries)-1), 0)
+	case "page-up":
+		m.treeCursor = max(m.treeCursor-m.treeRows(), 0)
+	case "page-down":
+		m.treeCursor = max(min(m.treeCursor+m.treeRows(), len(entries)-1), 0)
+	case "top":
+		m.treeCursor = 0
+	case "bottom":
+		m.treeCursor = max(len(entries)-1, 0)
+	case "play", "open":
+		if m.treeCursor >= len(entries) {
+			break
+		}
+		e := entries[m.treeCursor]
+		if e.dir {
+			m.enterTree(e.path, "")
+		} else if command == "play" {
+			m.closeTree()
+			return m.playEdition(e.path)
+		}
+	case "parent":
+		if path := crumbs(m.treeDir); len(path) > 1 {
+			m.enterTree(path[len(path)-2], m.treeDir)
+		}
+	case "pin":
+		if m.treeCursor < len(entries) {
+			m.pin(entries[m.treeCursor].path)
+			m.selectInTree(entries[m.treeCursor].path)
+		}
+	case "filter":
+		m.searchMode = true
+		m.prevFilter = m.filter
+		m.searchInput.Focus()
+		return m, textinput.Blink
+	case "sort", "then-sort":
+		if command == "sort" {
+			m.sortMode = (m.sortMode + 1) % len(sortModes)
+		} else {
+			m.thenMode = (m.thenMode + 1) % len(sortModes)
+		}
+		m.refresh()
+		if m.treeCursor < len(entries) {
+			m.selectInTree(entries[m.treeCursor].path)
+		}
+		m.status = m.sortStatus()
+	case "path":
+		m.crumbMode = true
+		m.crumbCursor = max(len(crumbs(m.treeDir))-2, 0)
+	case "close":
+		m.closeTree()
+	}
+	return m, nil
+}
+
+// updateCrumbs picks a folder from the breadcrumb to jump to.
+func (m model) updateCrumbs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	path := crumbs(m.treeDir)
+	switch keys().command("path", msg.String()) {
+	case "left":
+		m.crumbCursor = max(m.crumbCursor-1, 0)
+	case "right":
+		m.crumbCursor = min(m.crumbCursor+1, len(path)-1)
+	case "first":
+		m.crumbCursor = 0
+	case "go":
+		m.crumbMode = false
+		if m.crumbCursor < len(path)-1 {
+			// Select the folder on the way back down to where we were.
+			m.enterTree(path[m.crumbCursor], path[m.crumbCursor+1])
+		}
+	case "close":
+		m.crumbMode = false
+	}
+	return m, nil
+}
+
+// treeRows is the number of entries the folder view has room for.
+func (m model) treeRows() int {
+	if m.height == 0 {
+		return 20
+	}
+	return max(m.height-4, 1)
+}
+
+func (m model) viewTree() string {
+	var b strings.Builder
+	if m.crumbMode {
+		b.WriteString(m.fit(keys().help("Folders - %s/%s to pick a parent folder, %s to go there, %s to cancel", "path", "left", "right", "go", "close")) + "\n")
+	} else {
+		b.WriteString(m.fit(keys().help("Folders - %s/%s, %s to open or play, %s for the parent folder, %s to pick one from the path, %s to filter, %s/%s to sort, %s to pin, %s for the list", "folders", "up", "down", "play", "parent", "path", "filter", "sort", "then-sort", "pin", "close")) + "\n")
+	}
+	path := crumbs(m.treeDir)
+	names := make([]string, len(path))
+	for i, dir := range path {
+		names[i] = crumbName(dir)
+		switch {
+		case m.crumbMode && i == m.crumbCursor:
+			names[i] = selectedStyle.Render(names[i])
+		case i == len(path)-1:
+			names[i] = crumbStyle.Render(names[i])
+		}
+	}
+	b.WriteString(m.fit(strings.Join(names, " › ")) + "\n")
+	if m.searchMode {
+		b.WriteString("/" + m.searchInput.View() + m.viewSuggestions())
+	} else {
+		b.WriteString(m.fit(m.status))
+	}
+	b.WriteString("\n")
+
+	entries := m.treeEntries(m.treeDir)
+	if len(entries) == 0 {
+		b.WriteString(hiddenStyle.Render(tr("nothing here yet")))
+		return b.String()
+	}
+	rows := m.treeRows()
+	top := min(max(m.treeCursor-rows/2, 0), max(len(entries)-rows, 0))
+	for i := top; i < min(top+rows, len(entries)); i++ {
+		e := entries[i]
+		var line string
+		if e.dir {
+			line = fmt.Sprintf("%s%s%c (%d)", icon("folder"), crumbName(e.path), filepath.Separator, e.count)
+		} else {
+			line = videoIcon(e.path) + filepath.Base(e.path)
+			if stars := m.state.Ratings[e.path]; stars > 0 {
+				line += " " + renderStars(stars)
+			}
+		}
+		if m.state.isPinned(e.path) {
+			line += " " + hiddenStyle.Render("pinned")
+		}
+		line = m.fit(line)
+		if i == m.treeCursor && !m.crumbMode {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
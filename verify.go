@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// missingReason reports why path no longer leads to a file: "missing" when
+// nothing is there and "broken symlink" when a link points nowhere. It
+// returns "" for files that exist and for paths it cannot judge, such as
+// URLs or files under a library root that is not mounted right now.
+func missingReason(path string) string {
+	if !filepath.IsAbs(path) {
+		return ""
+	}
+	if root := rootOf(path); root != "" {
+		if _, err := os.Stat(root); err != nil {
+			return ""
+		}
+	}
+	_, err := os.Stat(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return ""
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return "broken symlink"
+	}
+	return "missing"
+}
+
+// verifyIndex removes the index entries and cached library files whose file
+// no longer exists, printing each one, and saves what changed.
+func verifyIndex(ix *index) error {
+	paths := make([]string, 0, len(ix.Entries))
+	for path := range ix.Entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	pruned := 0
+	for _, path := range paths {
+		if reason := missingReason(path); reason != "" {
+			fmt.Printf("Pruned %s (%s)\n", path, reason)
+			delete(ix.Entries, path)
+			pruned++
+		}
+	}
+
+	cache := loadLibraryCache(libraryCachePath())
+	cached := 0
+	for _, rc := range cache.Roots {
+		rc.Videos = rc.Videos.filter(func(video string) bool {
+			if missingReason(video) == "" {
+				return true
+			}
+			// Have the next scan read the folder again.
+			delete(rc.Dirs, filepath.Dir(video))
+			cached++
+			return false
+		})
+	}
+
+	fmt.Printf("Removed %d stale index entries and %d stale cached files\n", pruned, cached)
+	if pruned > 0 {
+		if err := ix.save(); err != nil {
+			return err
+		}
+	}
+	if cached > 0 {
+		return cache.save()
+	}
+	return nil
+}
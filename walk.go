@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// walkOptions tune how library directories are scanned.
+type walkOptions struct {
+	// follow descends into symlinked directories.
+	follow bool
+	// skipNetwork skips directories on network filesystems such as NFS or SMB.
+	skipNetwork bool
+	// timeout bounds reading a single directory, so a hung mount is skipped
+	// instead of freezing the scan. Zero means no limit.
+	timeout time.Duration
+	// progress, if set, counts the directories read.
+	progress *scanProgress
+	// workers is how many directories are read at once. Zero picks a
+	// number to suit the storage (see walkWorkers).
+	workers int
+	// ctx, if set, stops the walk from reading further directories once it
+	// is cancelled.
+	ctx context.Context
+}
+
+func (c config) walkOptions() walkOptions {
+	return walkOptions{
+		follow:      c.FollowSymlinks,
+		skipNetwork: c.SkipNetworkMounts,
+		timeout:     c.ScanTimeout.Duration,
+		workers:     c.ScanWorkers,
+	}
+}
+
+// walkWorkers picks how many directories of root to read at once: one on
+// a spinning disk, where parallel reads only add seeks, many on a network
+// share, where each read mostly waits on the round trip, and a few on
+// SSDs and storage that cannot be identified. It also names the storage,
+// for the log and bench.
+func walkWorkers(root string) (int, string) {
+	if isNetworkFS(root) {
+		return 16, "network"
+	}
+	switch rotational, known := isRotational(root); {
+	case !known:
+		return 4, "unknown"
+	case rotational:
+		return 1, "rotational"
+	}
+	return 8, "ssd"
+}
+
+var (
+	errNetworkMount = errors.New("network filesystem")
+	errScanTimeout  = errors.New("timed out")
+)
+
+// dirState is what a walk learned about one directory. It is kept between
+// scans so that directories whose modification time has not changed are not
+// read again.
+type dirState struct {
+	ModTime time.Time `json:"mtime"`
+	ID      fileID    `json:"id,omitempty"`
+	Files   []string  `json:"files,omitempty"`
+	Dirs    []string  `json:"dirs,omitempty"`
+	// Links are symlinks to directories, descended into only when following
+	// symlinks.
+	Links []string `json:"links,omitempty"`
+}
+
+// dirListing is the result of reading one directory.
+type dirListing struct {
+	state *dirState
+	err   error
+}
+
+// readDir identifies and lists dir, giving up after opts.timeout or once
+// opts.ctx is cancelled. A read
+// that times out keeps running in the background until the kernel returns.
+// When dir still has the modification time recorded in prev, prev is
+// returned without reading the directory again.
+func readDir(dir string, opts walkOptions, prev *dirState) dirListing {
+	ctx := opts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return dirListing{err: err}
+	}
+	done := make(chan dirListing, 1)
+	go func() {
+		defer catchPanic()
+		var l dirListing
+		if opts.skipNetwork && isNetworkFS(dir) {
+			l.err = errNetworkMount
+		} else {
+			l.state, l.err = listDir(dir, prev)
+		}
+		done <- l
+	}()
+	var timeout <-chan time.Time
+	if opts.timeout > 0 {
+		timeout = time.After(opts.timeout)
+	}
+	select {
+	case l := <-done:
+		return l
+	case <-timeout:
+		return dirListing{err: errScanTimeout}
+	case <-ctx.Done():
+		return dirListing{err: ctx.Err()}
+	}
+}
+
+func listDir(dir string, prev *dirState) (*dirState, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.ModTime.Equal(info.ModTime()) {
+		logger.Debug("directory unchanged", "path", dir)
+		// Listings cached before natural order was used are sorted too.
+		prev.sort()
+		return prev, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	st := &dirState{ModTime: info.ModTime()}
+	st.ID, _ = dirID(dir)
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			st.Dirs = append(st.Dirs, name)
+		case entry.Type()&fs.ModeSymlink != 0:
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				logger.Debug("skipped", "path", filepath.Join(dir, name), "reason", "broken symlink")
+			} else if info.IsDir() {
+				st.Links = append(st.Links, name)
+			} else {
+				st.Files = append(st.Files, name)
+			}
+		default:
+			st.Files = append(st.Files, name)
+		}
+	}
+	st.sort()
+	return st, nil
+}
+
+// sort puts the entries of st in natural order, "Episode 2" before "Episode
+// 10", which is the order the walk finds them in.
+func (st *dirState) sort() {
+	for _, names := range [][]string{st.Files, st.Dirs, st.Links} {
+		slices.SortFunc(names, naturalCompare)
+	}
+}
+
+// walkFiles calls fn for every non-directory entry below root. Symlinked
+// directories are descended into only when opts.follow is set. Every
+// directory is visited at most once, identified by device and inode where
+// available, so symlink cycles and recursive bind mounts cannot loop the
+// walk or list the same files twice. Unreadable subdirectories are logged
+// and skipped.
+//
+// prev holds the directories seen by an earlier walk, which are only read
+// again if they were modified since (see readDir). The directories seen by
+// this walk are returned for the next one.
+func walkFiles(root string, opts walkOptions, prev map[string]*dirState, fn func(path string)) (map[string]*dirState, error) {
+	workers := opts.workers
+	if workers <= 0 {
+		var storage string
+		workers, storage = walkWorkers(root)
+		logger.Debug("walking", "root", root, "storage", storage, "workers", workers)
+	}
+	// Directories are read ahead by up to workers goroutines, while the
+	// walk itself stays in order so the files are found in the same order
+	// every time.
+	sem := make(chan struct{}, workers)
+	readAhead := func(dirs []string) []chan dirListing {
+		listings := make([]chan dirListing, len(dirs))
+		for i, dir := range dirs {
+			listings[i] = make(chan dirListing, 1)
+			go func() {
+				defer catchPanic()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				listings[i] <- readDir(dir, opts, prev[dir])
+			}()
+		}
+		return listings
+	}
+
+	visited := map[fileID]bool{}
+	seen := map[string]*dirState{}
+	var walk func(dir string, l dirListing) error
+	walk = func(dir string, l dirListing) error {
+		if l.err != nil {
+			if !errors.Is(l.err, context.Canceled) {
+				logger.Warn("skipped directory", "path", dir, "err", l.err)
+			}
+			return l.err
+		}
+		seen[dir] = l.state
+		if opts.progress != nil {
+			opts.progress.dirs.Add(1)
+		}
+		if id := l.state.ID; id != "" {
+			if visited[id] {
+				logger.Debug("skipped", "path", dir, "reason", "directory already visited")
+				return nil
+			}
+			visited[id] = true
+		}
+		if marker := ignoreMarker(l.state.Files); marker != "" {
+			logger.Debug("skipped", "path", dir, "reason", marker+" marker")
+			return nil
+		}
+		for _, name := range l.state.Files {
+			fn(filepath.Join(dir, name))
+		}
+		var subdirs []string
+		for _, name := range l.state.Dirs {
+			subdirs = append(subdirs, filepath.Join(dir, name))
+		}
+		for _, name := range l.state.Links {
+			if opts.follow {
+				subdirs = append(subdirs, filepath.Join(dir, name))
+			} else {
+				logger.Debug("skipped", "path", filepath.Join(dir, name), "reason", "symlinked directory")
+			}
+		}
+		if workers == 1 {
+			for _, sub := range subdirs {
+				walk(sub, readDir(sub, opts, prev[sub]))
+			}
+			return nil
+		}
+		for i, listing := range readAhead(subdirs) {
+			walk(subdirs[i], <-listing)
+		}
+		return nil
+	}
+	err := walk(root, readDir(root, opts, prev[root]))
+	if opts.ctx != nil && opts.ctx.Err() != nil {
+		// The walk stopped part way, so what it saw is incomplete.
+		return seen, opts.ctx.Err()
+	}
+	return seen, err
+}
+
+// ignoreMarkers are the files that keep the scan out of the directory
+// holding them and everything below it, as with Android and Kodi.
+var ignoreMarkers = []string{".nomedia", ".mlignore-all"}
+
+// ignoreMarker returns the first of ignoreMarkers among files, or "".
+func ignoreMarker(files []string) string {
+	for _, name := range files {
+		if slices.Contains(ignoreMarkers, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func realPathID(path string) (fileID, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(real)
+	return fileID(abs), err
+}